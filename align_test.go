@@ -0,0 +1,26 @@
+package contraption
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDistribute(t *testing.T) {
+	for _, tt := range []struct {
+		sizes, props []float64
+		l            float64
+		want         []float64
+	}{
+		{[]float64{10, 0, 0}, []float64{0, 1, 3}, 50, []float64{10, 10, 30}},
+		{[]float64{10, 25}, []float64{0, 1}, 30, []float64{10, 25}},
+		{[]float64{10, 0}, []float64{0, 1}, math.Inf(1), []float64{10, 0}},
+		{[]float64{10, 20}, []float64{0, 0}, 100, []float64{10, 20}},
+	} {
+		got := slices.Clone(tt.sizes)
+		distribute(got, tt.props, tt.l, false)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("distribute(%v, %v, %v) = %v, want %v", tt.sizes, tt.props, tt.l, got, tt.want)
+		}
+	}
+}
@@ -32,13 +32,21 @@ func New(windowRect image.Rectangle) *Windower {
 	wer.Window.MakeContextCurrent()
 	gl.Init()
 
-	if glfw.ExtensionSupported("GLX_EXT_swap_control_tear") || glfw.ExtensionSupported("WGL_EXT_swap_control_tear") {
+	wer.Vsync(true)
+
+	return wer
+}
+
+// Vsync turns waiting for the screen refresh on and off.
+func (wer *Windower) Vsync(on bool) {
+	switch {
+	case !on:
+		glfw.SwapInterval(0)
+	case glfw.ExtensionSupported("GLX_EXT_swap_control_tear") || glfw.ExtensionSupported("WGL_EXT_swap_control_tear"):
 		glfw.SwapInterval(-1)
-	} else {
+	default:
 		glfw.SwapInterval(1)
 	}
-
-	return wer
 }
 
 func (wer *Windower) SetupInputCallbacks(emit func(ev any, pt geom.Point, t time.Time), u *contraption.Events) {
@@ -50,10 +58,15 @@ func (wer *Windower) SetupInputCallbacks(emit func(ev any, pt geom.Point, t time
 		n := time.Now()
 		emit(ev, u.Trace[0].Pt, n)
 	}
+	// emit3 is emit2 for events that know modifier keys.
+	emit3 := func(ev interface{}, mods glfw.ModifierKey) {
+		u.Hold(modsof(mods))
+		emit2(ev)
+	}
 	w.SetCursorPosCallback(func(_ *glfw.Window, xpos, ypos float64) {
 		emit(contraption.Hover{}, geom.Pt(xpos, ypos), time.Now())
 	})
-	w.SetMouseButtonCallback(func(_ *glfw.Window, button glfw.MouseButton, action glfw.Action, _ glfw.ModifierKey) {
+	w.SetMouseButtonCallback(func(_ *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
 		v := int(button)
 		switch button {
 		case glfw.MouseButtonLeft:
@@ -65,12 +78,12 @@ func (wer *Windower) SetupInputCallbacks(emit func(ev any, pt geom.Point, t time
 		}
 		switch action {
 		case glfw.Press:
-			emit2(contraption.Click(v))
+			emit3(contraption.Click(v), mods)
 		case glfw.Release:
-			emit2(contraption.Unclick(v))
+			emit3(contraption.Unclick(v), mods)
 		case glfw.Repeat:
-			emit2(contraption.Click(v))
-			emit2(contraption.Unclick(v))
+			emit3(contraption.Click(v), mods)
+			emit3(contraption.Unclick(v), mods)
 		}
 	})
 	w.SetScrollCallback(func(_ *glfw.Window, xoff, yoff float64) {
@@ -82,31 +95,115 @@ func (wer *Windower) SetupInputCallbacks(emit func(ev any, pt geom.Point, t time
 		}
 	})
 	w.SetKeyCallback(func(_ *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		k := keys[key]
 		switch action {
 		case glfw.Press:
-			emit2(contraption.Press{Key: key})
+			emit3(contraption.Press{Key: k, Rune: specialrunes[key]}, mods)
 		case glfw.Release:
-			emit2(contraption.Release{Key: key})
+			emit3(contraption.Release{Key: k}, mods)
 		case glfw.Repeat:
-			emit2(contraption.Release{Key: key})
-			emit2(contraption.Press{Key: key})
+			emit3(contraption.Release{Key: k}, mods)
+			emit3(contraption.Press{Key: k, Rune: specialrunes[key]}, mods)
 		}
 	})
 	w.SetCharCallback(func(w *glfw.Window, r rune) {
-		tr := &u.Trace[0]
-		switch e := tr.E.(type) {
-		case contraption.Press:
-			e.Rune = r
-			tr.E = e
-		default:
-			// panic(`unreachable`)
-		}
+		u.Char(r)
 	})
 	w.SetDropCallback(func(w *glfw.Window, names []string) {
 		emit2(contraption.Drop{Paths: names})
 	})
 }
 
+// modsof converts GLFW modifiers, which don't tell left keys from right ones.
+func modsof(mods glfw.ModifierKey) (m contraption.Mods) {
+	if mods&glfw.ModShift > 0 {
+		m |= contraption.ModShift
+	}
+	if mods&glfw.ModControl > 0 {
+		m |= contraption.ModCtrl
+	}
+	if mods&glfw.ModAlt > 0 {
+		m |= contraption.ModAlt
+	}
+	if mods&glfw.ModSuper > 0 {
+		m |= contraption.ModSuper
+	}
+	return
+}
+
+// keys translates GLFW keys, which are missing here, to KeyUnknown.
+var keys = map[glfw.Key]contraption.Key{
+	glfw.KeyA: contraption.KeyA, glfw.KeyB: contraption.KeyB, glfw.KeyC: contraption.KeyC, glfw.KeyD: contraption.KeyD,
+	glfw.KeyE: contraption.KeyE, glfw.KeyF: contraption.KeyF, glfw.KeyG: contraption.KeyG, glfw.KeyH: contraption.KeyH,
+	glfw.KeyI: contraption.KeyI, glfw.KeyJ: contraption.KeyJ, glfw.KeyK: contraption.KeyK, glfw.KeyL: contraption.KeyL,
+	glfw.KeyM: contraption.KeyM, glfw.KeyN: contraption.KeyN, glfw.KeyO: contraption.KeyO, glfw.KeyP: contraption.KeyP,
+	glfw.KeyQ: contraption.KeyQ, glfw.KeyR: contraption.KeyR, glfw.KeyS: contraption.KeyS, glfw.KeyT: contraption.KeyT,
+	glfw.KeyU: contraption.KeyU, glfw.KeyV: contraption.KeyV, glfw.KeyW: contraption.KeyW, glfw.KeyX: contraption.KeyX,
+	glfw.KeyY: contraption.KeyY, glfw.KeyZ: contraption.KeyZ,
+
+	glfw.Key0: contraption.Key0, glfw.Key1: contraption.Key1, glfw.Key2: contraption.Key2, glfw.Key3: contraption.Key3,
+	glfw.Key4: contraption.Key4, glfw.Key5: contraption.Key5, glfw.Key6: contraption.Key6, glfw.Key7: contraption.Key7,
+	glfw.Key8: contraption.Key8, glfw.Key9: contraption.Key9,
+
+	glfw.KeyF1: contraption.KeyF1, glfw.KeyF2: contraption.KeyF2, glfw.KeyF3: contraption.KeyF3, glfw.KeyF4: contraption.KeyF4,
+	glfw.KeyF5: contraption.KeyF5, glfw.KeyF6: contraption.KeyF6, glfw.KeyF7: contraption.KeyF7, glfw.KeyF8: contraption.KeyF8,
+	glfw.KeyF9: contraption.KeyF9, glfw.KeyF10: contraption.KeyF10, glfw.KeyF11: contraption.KeyF11, glfw.KeyF12: contraption.KeyF12,
+
+	glfw.KeySpace:        contraption.KeySpace,
+	glfw.KeyComma:        contraption.KeyComma,
+	glfw.KeyPeriod:       contraption.KeyPeriod,
+	glfw.KeySlash:        contraption.KeySlash,
+	glfw.KeyBackslash:    contraption.KeyBackslash,
+	glfw.KeySemicolon:    contraption.KeySemicolon,
+	glfw.KeyApostrophe:   contraption.KeyApostrophe,
+	glfw.KeyGraveAccent:  contraption.KeyGrave,
+	glfw.KeyMinus:        contraption.KeyMinus,
+	glfw.KeyEqual:        contraption.KeyEqual,
+	glfw.KeyLeftBracket:  contraption.KeyLeftBracket,
+	glfw.KeyRightBracket: contraption.KeyRightBracket,
+
+	glfw.KeyEscape:      contraption.KeyEscape,
+	glfw.KeyEnter:       contraption.KeyEnter,
+	glfw.KeyKPEnter:     contraption.KeyEnter,
+	glfw.KeyTab:         contraption.KeyTab,
+	glfw.KeyBackspace:   contraption.KeyBackspace,
+	glfw.KeyInsert:      contraption.KeyInsert,
+	glfw.KeyDelete:      contraption.KeyDelete,
+	glfw.KeyHome:        contraption.KeyHome,
+	glfw.KeyEnd:         contraption.KeyEnd,
+	glfw.KeyPageUp:      contraption.KeyPageUp,
+	glfw.KeyPageDown:    contraption.KeyPageDown,
+	glfw.KeyLeft:        contraption.KeyLeft,
+	glfw.KeyRight:       contraption.KeyRight,
+	glfw.KeyUp:          contraption.KeyUp,
+	glfw.KeyDown:        contraption.KeyDown,
+	glfw.KeyPrintScreen: contraption.KeyPrintScreen,
+	glfw.KeyScrollLock:  contraption.KeyScrollLock,
+	glfw.KeyNumLock:     contraption.KeyNumLock,
+	glfw.KeyCapsLock:    contraption.KeyCapsLock,
+	glfw.KeyPause:       contraption.KeyPause,
+	glfw.KeyMenu:        contraption.KeyMenu,
+
+	glfw.KeyLeftShift:    contraption.KeyLShift,
+	glfw.KeyRightShift:   contraption.KeyRShift,
+	glfw.KeyLeftControl:  contraption.KeyLCtrl,
+	glfw.KeyRightControl: contraption.KeyRCtrl,
+	glfw.KeyLeftAlt:      contraption.KeyLAlt,
+	glfw.KeyRightAlt:     contraption.KeyRAlt,
+	glfw.KeyLeftSuper:    contraption.KeyLSuper,
+	glfw.KeyRightSuper:   contraption.KeyRSuper,
+}
+
+// specialrunes are runes of editing keys that don't enter text.
+var specialrunes = map[glfw.Key]rune{
+	glfw.KeyBackspace: contraption.RuneBackspace,
+	glfw.KeyDelete:    contraption.RuneDelete,
+	glfw.KeyLeft:      contraption.RuneLeft,
+	glfw.KeyDown:      contraption.RuneDown,
+	glfw.KeyUp:        contraption.RuneUp,
+	glfw.KeyRight:     contraption.RuneRight,
+}
+
 func (wer *Windower) WaitEvents(_ *contraption.Events) {
 	glfw.WaitEvents()
 }
@@ -0,0 +1,154 @@
+// Package headless implements a Windower without a window.
+//
+// Events are not received from the operating system, but are pushed by the program,
+// so the World can be driven by tests or run offscreen, e.g. with the software renderer.
+//
+//	wer := headless.New(640, 480, 1)
+//	wo := contraption.New(wer, software.New(), contraption.Config{})
+//	wer.Push(
+//		headless.Event{E: contraption.Hover{}, Pt: geom.Pt(10, 10)},
+//		headless.Event{E: contraption.Click(1)},
+//		headless.Event{E: contraption.Unclick(1)})
+//	for wo.Next() {
+//		...
+//		wo.Develop()
+//	}
+package headless
+
+import (
+	"time"
+
+	"github.com/neputevshina/contraption"
+	"github.com/neputevshina/geom"
+)
+
+// Event is a scripted input event.
+type Event struct {
+	// E is any of the contraption event types: Click, Unclick, Hover, Scroll, Sweep, Press, Release, Drop.
+	E any
+	// Pt is the new position of the cursor. It is used only for Hover, like in the windowed backends
+	// other events happen at the last position of the cursor.
+	Pt geom.Point
+	// Char is the text entered with Press. Like in the windowed backends, it is delivered
+	// after the Press, see (*contraption.Events).Char.
+	Char rune
+	// Dt is the time passed since the previous event, by the Clock of the Windower.
+	// It is at least a nanosecond, because an event is matched as fresh only if it is newer
+	// than the frame it arrived at.
+	Dt time.Duration
+}
+
+// Windower is a Windower of a fixed size that delivers the queued events.
+//
+// Every call to PollEvents or WaitEvents delivers at most one event from the queue.
+// An event becomes visible in Events.Trace during the next frame.
+type Windower struct {
+	// W and H are the viewport size reported by Next, Scale is its content scale.
+	W, H  int
+	Scale float64
+	// If Exhaustible is set, Next returns false after the queue was drained and
+	// the World had to wait for new events.
+	Exhaustible bool
+	// Frames is the count of frames ended with Develop.
+	Frames int
+	// If Clock is not zero, it is the time of the World instead of the system time.
+	// It is advanced only by Dt of the delivered events, so runs are reproducible.
+	Clock time.Time
+
+	emit   func(ev any, pt geom.Point, t time.Time)
+	u      *contraption.Events
+	queue  []Event
+	pt     geom.Point
+	closed bool
+}
+
+func New(w, h int, scale float64) *Windower {
+	return &Windower{
+		W:     w,
+		H:     h,
+		Scale: scale,
+	}
+}
+
+// Push appends events to the queue.
+func (wer *Windower) Push(evs ...Event) {
+	wer.queue = append(wer.queue, evs...)
+}
+
+// Pending returns the count of events that are not delivered yet.
+func (wer *Windower) Pending() int {
+	return len(wer.queue)
+}
+
+// Emit delivers the event immediately, bypassing the queue.
+// Emit can be used only after the Windower was passed to contraption.New.
+func (wer *Windower) Emit(ev any, pt geom.Point) {
+	if wer.emit == nil {
+		panic(`contraption/headless: Emit before the input callbacks were set up`)
+	}
+	if _, ok := ev.(contraption.Hover); ok {
+		wer.pt = pt
+	}
+	wer.emit(ev, wer.pt, wer.Now())
+}
+
+// Now returns the Clock, or the system time if the Clock is zero.
+func (wer *Windower) Now() time.Time {
+	if wer.Clock.IsZero() {
+		return time.Now()
+	}
+	return wer.Clock
+}
+
+// Cursor returns the current position of the cursor.
+func (wer *Windower) Cursor() geom.Point {
+	return wer.pt
+}
+
+// Close makes the next call to Next return false.
+func (wer *Windower) Close() {
+	wer.closed = true
+}
+
+func (wer *Windower) SetupInputCallbacks(emit func(ev any, pt geom.Point, t time.Time), u *contraption.Events) {
+	wer.emit = emit
+	wer.u = u
+}
+
+func (wer *Windower) deliver() bool {
+	if len(wer.queue) == 0 {
+		return false
+	}
+	e := wer.queue[0]
+	wer.queue = wer.queue[1:]
+	if !wer.Clock.IsZero() {
+		wer.Clock = wer.Clock.Add(max(e.Dt, time.Nanosecond))
+	}
+	wer.Emit(e.E, e.Pt)
+	if e.Char != 0 {
+		wer.u.Char(e.Char)
+	}
+	return true
+}
+
+func (wer *Windower) PollEvents(_ *contraption.Events) {
+	wer.deliver()
+}
+
+func (wer *Windower) WaitEvents(_ *contraption.Events) {
+	// There is no one to wait for, so the World is not blocked.
+	if !wer.deliver() && wer.Exhaustible {
+		wer.closed = true
+	}
+}
+
+func (wer *Windower) Develop(_ *contraption.Events) {
+	wer.Frames++
+}
+
+func (wer *Windower) Next(_ *contraption.Events) (ok bool, w, h int, scale float64) {
+	if wer.closed {
+		return false, 0, 0, 0
+	}
+	return true, wer.W, wer.H, wer.Scale
+}
@@ -0,0 +1,509 @@
+// Package interp keeps the drawing state while a backend interprets the draw list.
+//
+// It follows the semantics of nanovgo: transformations premultiply the current one,
+// paints are transformed when set, path points are transformed when added and
+// all shapes are converted to line and cubic Bézier segments.
+// Backends that don't draw through nanovgo call Step for every operation
+// and handle only drawing itself: Fill, Stroke, TextRune and frames.
+package interp
+
+import (
+	"math"
+
+	"github.com/neputevshina/contraption"
+	"github.com/neputevshina/contraption/nanovgo"
+	"github.com/neputevshina/contraption/op"
+)
+
+type Point struct {
+	X, Y float32
+}
+
+// Cmd is a path command. Op is one of 'M', 'L', 'C' and 'Z' and the points
+// are already transformed by the transformation at the moment of adding.
+// 'C' uses all three points, 'M' and 'L' only the first.
+type Cmd struct {
+	Op  byte
+	Pts [3]Point
+}
+
+// Last returns the end point of the command.
+func (c Cmd) Last() Point {
+	if c.Op == 'C' {
+		return c.Pts[2]
+	}
+	return c.Pts[0]
+}
+
+// Paint is a nanovgo.Paint with accessible fields.
+type Paint struct {
+	// Xform maps paint space to the space of the path points.
+	Xform   nanovgo.TransformMatrix
+	Extent  [2]float32
+	Radius  float32
+	Feather float32
+	Inner   nanovgo.Color
+	Outer   nanovgo.Color
+	Image   int
+}
+
+func ColorPaint(c nanovgo.Color) Paint {
+	return Paint{
+		Xform:   nanovgo.IdentityMatrix(),
+		Feather: 1,
+		Inner:   c,
+		Outer:   c,
+	}
+}
+
+func ConvPaint(p nanovgo.Paint) (q Paint) {
+	q.Xform, q.Extent, q.Radius, q.Feather, q.Inner, q.Outer = p.Params()
+	q.Image = p.Image
+	return
+}
+
+// Solid reports if the paint has the same color everywhere.
+func (p Paint) Solid() bool {
+	return p.Image == 0 && p.Inner == p.Outer
+}
+
+// Linear reports if the paint is a linear gradient and returns its ends in paint space.
+func (p Paint) Linear() (ok bool, y0, y1 float32) {
+	if p.Image != 0 || p.Radius != 0 || p.Extent[0] < 1e4 {
+		return false, 0, 0
+	}
+	return true, p.Extent[1] - p.Feather*0.5, p.Extent[1] + p.Feather*0.5
+}
+
+// Radial reports if the paint is a radial gradient and returns its radii in paint space.
+func (p Paint) Radial() (ok bool, r0, r1 float32) {
+	if p.Image != 0 || p.Extent[0] != p.Extent[1] || (p.Radius != 0 && p.Radius != p.Extent[0]) {
+		return false, 0, 0
+	}
+	return true, max(0, p.Extent[0]-p.Feather*0.5), p.Extent[0] + p.Feather*0.5
+}
+
+type State struct {
+	Xform       nanovgo.TransformMatrix
+	Scissor     nanovgo.TransformMatrix
+	ScissorExt  [2]float32
+	Fill        Paint
+	Stroke      Paint
+	StrokeWidth float32
+	MiterLimit  float32
+	LineCap     nanovgo.LineCap
+	LineJoin    nanovgo.LineCap
+	Alpha       float32
+	Font        int
+	FontSize    float32
+}
+
+func (s *State) Reset() {
+	*s = State{
+		Xform:       nanovgo.IdentityMatrix(),
+		ScissorExt:  [2]float32{-1, -1},
+		Fill:        ColorPaint(nanovgo.RGBA(255, 255, 255, 255)),
+		Stroke:      ColorPaint(nanovgo.RGBA(0, 0, 0, 255)),
+		StrokeWidth: 1,
+		MiterLimit:  10,
+		LineCap:     nanovgo.Butt,
+		LineJoin:    nanovgo.Miter,
+		Alpha:       1,
+		Font:        s.Font,
+		FontSize:    s.FontSize,
+	}
+}
+
+// Scissored reports if the scissor is set.
+func (s *State) Scissored() bool {
+	return s.ScissorExt[0] > -0.5 && s.ScissorExt[1] > -0.5
+}
+
+// StrokeScale returns the stroke width in the space of the path points.
+func (s *State) StrokeScale() float32 {
+	return clampf(s.StrokeWidth*s.Xform.GetAverageScale(), 0, 200)
+}
+
+// Interp is the interpreter state. Zero value is ready to use.
+type Interp struct {
+	States []State
+	Path   []Cmd
+
+	// Points closer than DistTol are considered equal. Set by BeginFrame.
+	DistTol float32
+
+	// Last point of the path in user space, needed for QuadTo and ArcTo.
+	cmdx, cmdy float32
+}
+
+// State returns the current state.
+func (ip *Interp) State() *State {
+	if len(ip.States) == 0 {
+		ip.States = append(ip.States, State{})
+		ip.States[0].Reset()
+	}
+	return &ip.States[len(ip.States)-1]
+}
+
+// BeginFrame resets the state stack and the path.
+func (ip *Interp) BeginFrame(ratio float32) {
+	if ratio <= 0 {
+		ratio = 1
+	}
+	ip.DistTol = 0.01 / ratio
+	ip.States = ip.States[:0]
+	ip.Path = ip.Path[:0]
+	ip.State()
+}
+
+// Step applies the state or the path operation and reports if it was one.
+func (ip *Interp) Step(l *contraption.RenderOp) bool {
+	s := ip.State()
+	switch l.Tag {
+	case op.Also:
+	case op.Arc:
+		ip.Arc(f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[3]), f32(l.Args[4]), l.Direction)
+	case op.ArcTo:
+		ip.ArcTo(f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[3]), f32(l.Args[4]))
+	case op.BeginPath:
+		ip.Path = ip.Path[:0]
+	case op.BezierTo:
+		ip.BezierTo(f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[3]), f32(l.Args[4]), f32(l.Args[5]))
+	case op.Circle:
+		ip.Ellipse(f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[2]))
+	case op.ClosePath:
+		ip.ClosePath()
+	case op.Ellipse:
+		ip.Ellipse(f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[3]))
+	case op.IntersectScissor:
+		ip.IntersectScissor(f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[3]))
+	case op.LineTo:
+		ip.LineTo(f32(l.Args[0]), f32(l.Args[1]))
+	case op.MoveTo:
+		ip.MoveTo(f32(l.Args[0]), f32(l.Args[1]))
+	case op.PathWinding:
+		// Winding enforcement is disabled in nanovgo, paths are filled by the nonzero rule.
+	case op.QuadTo:
+		ip.QuadTo(f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[3]))
+	case op.Rect:
+		ip.Rect(f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[3]))
+	case op.Reset:
+		s.Reset()
+	case op.ResetScissor:
+		s.Scissor = nanovgo.TransformMatrix{}
+		s.ScissorExt = [2]float32{-1, -1}
+	case op.ResetTransform:
+		s.Xform = nanovgo.IdentityMatrix()
+	case op.Restore:
+		if len(ip.States) > 1 {
+			ip.States = ip.States[:len(ip.States)-1]
+		}
+	case op.Rotate:
+		s.Xform = s.Xform.PreMultiply(nanovgo.RotateMatrix(f32(l.Args[0])))
+	case op.RoundedRect:
+		ip.RoundedRect(f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[3]), f32(l.Args[4]))
+	case op.Save:
+		ip.States = append(ip.States, *s)
+	case op.Scale:
+		s.Xform = s.Xform.PreMultiply(nanovgo.ScaleMatrix(f32(l.Args[0]), f32(l.Args[1])))
+	case op.Scissor:
+		ip.Scissor(f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[3]))
+	case op.SetFillColor:
+		s.Fill = ColorPaint(l.Fillc)
+	case op.SetFillPaint:
+		s.Fill = ConvPaint(l.Fillp)
+		s.Fill.Xform = s.Fill.Xform.Multiply(s.Xform)
+	case op.SetFontFaceID:
+		s.Font = l.Hfont
+	case op.SetFontSize:
+		s.FontSize = f32(l.Fontsiz)
+	case op.SetGlobalAlpha:
+		s.Alpha = f32(l.Args[0])
+	case op.SetLineCap:
+		s.LineCap = l.Linecap
+	case op.SetLineJoin:
+		s.LineJoin = l.Linecap
+	case op.SetMiterLimit:
+		s.MiterLimit = f32(l.Args[0])
+	case op.SetStrokeColor:
+		s.Stroke = ColorPaint(l.Strokec)
+	case op.SetStrokePaint:
+		s.Stroke = ConvPaint(l.Strokep)
+		s.Stroke.Xform = s.Stroke.Xform.Multiply(s.Xform)
+	case op.SetStrokeWidth:
+		s.StrokeWidth = f32(l.Strokew)
+	case op.SetTransform:
+		s.Xform = s.Xform.PreMultiply(l.TransformMatrix)
+	case op.SetTransformByValue:
+		t := nanovgo.TransformMatrix{f32(l.Args[0]), f32(l.Args[1]), f32(l.Args[2]), f32(l.Args[3]), f32(l.Args[4]), f32(l.Args[5])}
+		s.Xform = s.Xform.PreMultiply(t)
+	case op.SkewX:
+		s.Xform = s.Xform.PreMultiply(nanovgo.SkewXMatrix(f32(l.Args[0])))
+	case op.SkewY:
+		s.Xform = s.Xform.PreMultiply(nanovgo.SkewYMatrix(f32(l.Args[0])))
+	case op.Translate:
+		s.Xform = s.Xform.PreMultiply(nanovgo.TranslateMatrix(f32(l.Args[0]), f32(l.Args[1])))
+	default:
+		return false
+	}
+	return true
+}
+
+/* Path construction */
+
+func (ip *Interp) add(c Cmd) {
+	ip.Path = append(ip.Path, c)
+}
+
+func (ip *Interp) tr(x, y float32) Point {
+	x, y = ip.State().Xform.TransformPoint(x, y)
+	return Point{x, y}
+}
+
+func (ip *Interp) MoveTo(x, y float32) {
+	ip.cmdx, ip.cmdy = x, y
+	ip.add(Cmd{Op: 'M', Pts: [3]Point{ip.tr(x, y)}})
+}
+
+func (ip *Interp) LineTo(x, y float32) {
+	ip.cmdx, ip.cmdy = x, y
+	ip.add(Cmd{Op: 'L', Pts: [3]Point{ip.tr(x, y)}})
+}
+
+func (ip *Interp) BezierTo(c1x, c1y, c2x, c2y, x, y float32) {
+	ip.cmdx, ip.cmdy = x, y
+	ip.add(Cmd{Op: 'C', Pts: [3]Point{ip.tr(c1x, c1y), ip.tr(c2x, c2y), ip.tr(x, y)}})
+}
+
+func (ip *Interp) QuadTo(cx, cy, x, y float32) {
+	x0 := ip.cmdx
+	y0 := ip.cmdy
+	ip.BezierTo(
+		x0+2.0/3.0*(cx-x0), y0+2.0/3.0*(cy-y0),
+		x+2.0/3.0*(cx-x), y+2.0/3.0*(cy-y),
+		x, y)
+}
+
+func (ip *Interp) ClosePath() {
+	ip.add(Cmd{Op: 'Z'})
+}
+
+func (ip *Interp) Arc(cx, cy, r, a0, a1 float32, dir nanovgo.Direction) {
+	move := len(ip.Path) == 0
+
+	da := a1 - a0
+	if dir == nanovgo.Clockwise {
+		if absf(da) >= math.Pi*2 {
+			da = math.Pi * 2
+		} else {
+			for da < 0 {
+				da += math.Pi * 2
+			}
+		}
+	} else {
+		if absf(da) >= math.Pi*2 {
+			da = -math.Pi * 2
+		} else {
+			for da > 0 {
+				da -= math.Pi * 2
+			}
+		}
+	}
+	// Split arc into max 90 degree segments.
+	ndivs := max(1, min(5, int(absf(da)/(math.Pi*0.5)+0.5)))
+	hda := float64(da) / float64(ndivs) / 2
+	kappa := absf(f32(4.0 / 3.0 * (1.0 - math.Cos(hda)) / math.Sin(hda)))
+	if dir == nanovgo.CounterClockwise {
+		kappa = -kappa
+	}
+
+	var px, py, ptanx, ptany float32
+	for i := 0; i <= ndivs; i++ {
+		a := float64(a0 + da*float32(i)/float32(ndivs))
+		dx, dy := f32(math.Cos(a)), f32(math.Sin(a))
+		x := cx + dx*r
+		y := cy + dy*r
+		tanx := -dy * r * kappa
+		tany := dx * r * kappa
+		if i == 0 {
+			if move {
+				ip.MoveTo(x, y)
+			} else {
+				ip.LineTo(x, y)
+			}
+		} else {
+			ip.BezierTo(px+ptanx, py+ptany, x-tanx, y-tany, x, y)
+		}
+		px, py = x, y
+		ptanx, ptany = tanx, tany
+	}
+}
+
+func (ip *Interp) ArcTo(x1, y1, x2, y2, radius float32) {
+	if len(ip.Path) == 0 {
+		return
+	}
+	x0 := ip.cmdx
+	y0 := ip.cmdy
+	tol := ip.DistTol
+	if ptequals(x0, y0, x1, y1, tol) ||
+		ptequals(x1, y1, x2, y2, tol) ||
+		distptseg(x1, y1, x0, y0, x2, y2) < tol*tol ||
+		radius < tol {
+		ip.LineTo(x1, y1)
+		return
+	}
+
+	// Calculate tangential circle to lines (x0,y0)-(x1,y1) and (x1,y1)-(x2,y2).
+	dx0, dy0 := normalize(x0-x1, y0-y1)
+	dx1, dy1 := normalize(x2-x1, y2-y1)
+	a := math.Acos(float64(dx0*dx1 + dy0*dy1))
+	d := radius / f32(math.Tan(a/2))
+	if d > 10000 {
+		ip.LineTo(x1, y1)
+		return
+	}
+
+	var cx, cy, a0, a1 float32
+	var dir nanovgo.Direction
+	if dx0*dy1-dx1*dy0 > 0 {
+		cx = x1 + dx0*d + dy0*radius
+		cy = y1 + dy0*d + -dx0*radius
+		a0 = atan2(dx0, -dy0)
+		a1 = atan2(-dx1, dy1)
+		dir = nanovgo.Clockwise
+	} else {
+		cx = x1 + dx0*d + -dy0*radius
+		cy = y1 + dy0*d + dx0*radius
+		a0 = atan2(-dx0, dy0)
+		a1 = atan2(dx1, -dy1)
+		dir = nanovgo.CounterClockwise
+	}
+	ip.Arc(cx, cy, radius, a0, a1, dir)
+}
+
+func (ip *Interp) Rect(x, y, w, h float32) {
+	ip.MoveTo(x, y)
+	ip.LineTo(x, y+h)
+	ip.LineTo(x+w, y+h)
+	ip.LineTo(x+w, y)
+	ip.ClosePath()
+}
+
+func (ip *Interp) RoundedRect(x, y, w, h, r float32) {
+	if r < 0.1 {
+		ip.Rect(x, y, w, h)
+		return
+	}
+	const k = 1 - nanovgo.Kappa90
+	rx := min(r, absf(w)*0.5) * signf(w)
+	ry := min(r, absf(h)*0.5) * signf(h)
+	ip.MoveTo(x, y+ry)
+	ip.LineTo(x, y+h-ry)
+	ip.BezierTo(x, y+h-ry*k, x+rx*k, y+h, x+rx, y+h)
+	ip.LineTo(x+w-rx, y+h)
+	ip.BezierTo(x+w-rx*k, y+h, x+w, y+h-ry*k, x+w, y+h-ry)
+	ip.LineTo(x+w, y+ry)
+	ip.BezierTo(x+w, y+ry*k, x+w-rx*k, y, x+w-rx, y)
+	ip.LineTo(x+rx, y)
+	ip.BezierTo(x+rx*k, y, x, y+ry*k, x, y+ry)
+	ip.ClosePath()
+}
+
+func (ip *Interp) Ellipse(cx, cy, rx, ry float32) {
+	const k = nanovgo.Kappa90
+	ip.MoveTo(cx-rx, cy)
+	ip.BezierTo(cx-rx, cy+ry*k, cx-rx*k, cy+ry, cx, cy+ry)
+	ip.BezierTo(cx+rx*k, cy+ry, cx+rx, cy+ry*k, cx+rx, cy)
+	ip.BezierTo(cx+rx, cy-ry*k, cx+rx*k, cy-ry, cx, cy-ry)
+	ip.BezierTo(cx-rx*k, cy-ry, cx-rx, cy-ry*k, cx-rx, cy)
+	ip.ClosePath()
+}
+
+/* Scissoring */
+
+// Scissor sets the current scissor rectangle, transformed by the current transform.
+func (ip *Interp) Scissor(x, y, w, h float32) {
+	s := ip.State()
+	w = max(0, w)
+	h = max(0, h)
+	s.Scissor = nanovgo.TranslateMatrix(x+w*0.5, y+h*0.5).Multiply(s.Xform)
+	s.ScissorExt = [2]float32{w * 0.5, h * 0.5}
+}
+
+// IntersectScissor intersects the current scissor with the rectangle.
+// If the rotation of the previous scissor differs, the result is the bounding rectangle
+// of the previous scissor in the current space intersected with the rectangle.
+func (ip *Interp) IntersectScissor(x, y, w, h float32) {
+	s := ip.State()
+	if !s.Scissored() {
+		ip.Scissor(x, y, w, h)
+		return
+	}
+
+	pt := s.Scissor.Multiply(s.Xform.Inverse())
+	ex := s.ScissorExt[0]
+	ey := s.ScissorExt[1]
+	tex := ex*absf(pt[0]) + ey*absf(pt[2])
+	tey := ex*absf(pt[1]) + ey*absf(pt[3])
+
+	ax, ay := max(pt[4]-tex, x), max(pt[5]-tey, y)
+	bx, by := min(pt[4]+tex, x+w), min(pt[5]+tey, y+h)
+	ip.Scissor(ax, ay, max(0, bx-ax), max(0, by-ay))
+}
+
+/* Math */
+
+func f32(x float64) float32 { return float32(x) }
+
+func absf(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func signf(x float32) float32 {
+	if x >= 0 {
+		return 1
+	}
+	return -1
+}
+
+func clampf(x, a, b float32) float32 {
+	return max(a, min(b, x))
+}
+
+func atan2(y, x float32) float32 {
+	return f32(math.Atan2(float64(y), float64(x)))
+}
+
+func normalize(x, y float32) (float32, float32) {
+	d := f32(math.Sqrt(float64(x*x + y*y)))
+	if d > 1e-6 {
+		return x / d, y / d
+	}
+	return x, y
+}
+
+func ptequals(x1, y1, x2, y2, tol float32) bool {
+	dx := x2 - x1
+	dy := y2 - y1
+	return dx*dx+dy*dy < tol*tol
+}
+
+func distptseg(x, y, px, py, qx, qy float32) float32 {
+	pqx := qx - px
+	pqy := qy - py
+	dx := x - px
+	dy := y - py
+	d := pqx*pqx + pqy*pqy
+	t := pqx*dx + pqy*dy
+	if d > 0 {
+		t /= d
+	}
+	t = clampf(t, 0, 1)
+	dx = px + t*pqx - x
+	dy = py + t*pqy - y
+	return dx*dx + dy*dy
+}
@@ -0,0 +1,594 @@
+// Package pdf implements a Renderer that collects frames as pages of a PDF document.
+//
+// Every frame between BeginFrame and EndFrame becomes a page. Paths are kept as vector
+// paths, solid paints become colors, gradients become shading patterns and image patterns
+// become image XObjects. TrueType fonts used by text are embedded to the document.
+//
+// A unit of the frame is a typographic point. Frame sizes in millimetres are set by
+// (*World).Page, so a page per Sorm subtree can be made like this:
+//
+//	doc := pdf.New()
+//	for _, chapter := range chapters {
+//		wo.Next()
+//		wo.Page(210, 297)
+//		wo.Root(chapter(wo))
+//		wo.Export(doc)
+//		wo.Develop()
+//	}
+//	doc.WriteTo(f)
+//
+// Renderer can be used as the main renderer of a World too, then every Develop adds a page.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/neputevshina/contraption"
+	"github.com/neputevshina/contraption/backends/interp"
+	"github.com/neputevshina/contraption/nanovgo"
+	"github.com/neputevshina/contraption/op"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Kinds of the named resources.
+const (
+	resExtGState = iota
+	resPattern
+	resXObject
+	resFont
+	resCount
+)
+
+var resKinds = [resCount]string{`/ExtGState`, `/Pattern`, `/XObject`, `/Font`}
+
+type Renderer struct {
+	ip   interp.Interp
+	page bytes.Buffer
+	w, h float64
+
+	// objs are the bodies of objects, object number is the index plus one.
+	// Reserved objects are nil until the document is written.
+	objs      [][]byte
+	pagesid   int
+	resid     int
+	pages     []int
+	res       [resCount][]string
+	resources map[string]string
+	shadings  map[string]int
+
+	images map[int]string
+	fonts  map[int]*pdffont
+	sbuf   sfnt.Buffer
+}
+
+type pdffont struct {
+	h    int
+	name string
+	ref  string
+	id   int
+	f    *contraption.Font
+	// runes and widths are indexed by glyph ids used in the document.
+	runes  map[sfnt.GlyphIndex]rune
+	widths map[sfnt.GlyphIndex]float64
+}
+
+func New() *Renderer {
+	rer := &Renderer{
+		resources: map[string]string{},
+		shadings:  map[string]int{},
+		images:    map[int]string{},
+		fonts:     map[int]*pdffont{},
+	}
+	rer.pagesid = rer.reserve()
+	rer.resid = rer.reserve()
+	return rer
+}
+
+// Pages returns the count of pages in the document.
+func (rer *Renderer) Pages() int {
+	return len(rer.pages)
+}
+
+func (rer *Renderer) Run(c *contraption.Context) {
+	for i := range c.Log {
+		l := &c.Log[i]
+		if rer.ip.Step(l) {
+			continue
+		}
+		switch l.Tag {
+		case op.BeginFrame:
+			rer.beginFrame(l)
+		case op.EndFrame:
+			rer.endFrame()
+		case op.Block:
+			panic(`unimplemented`)
+		case op.CancelFrame:
+			panic(`unimplemented`)
+		case op.CreateFontFromMemory:
+		case op.CreateImageFromGoImage, op.CreateImageRGBA, op.UpdateImage, op.DeleteImage:
+			delete(rer.images, l.Himage)
+		case op.Fill:
+			rer.fill(c)
+		case op.Stroke:
+			rer.stroke(c)
+		case op.TextRune:
+			rer.text(c, l)
+		case op.SetFontBlur, op.SetTextAlign, op.SetTextLetterSpacing, op.SetTextLineHeight:
+		case op.SetFontFace, op.DebugDumpPathCache, op.Delete, op.FindFont:
+			panic(`unimplemented`)
+		case op.CurrentTransform, op.FontBlur, op.FontFace, op.FontFaceID, op.FontSize, op.GlobalAlpha,
+			op.ImageSize, op.LineCap, op.LineJoin, op.MiterLimit, op.StrokeWidth, op.TextAlign,
+			op.TextBounds, op.TextLetterSpacing, op.TextLineHeight, op.TextMetrics:
+			panic(`getter, unreachable`)
+		default:
+			panic(`unreachable`)
+		}
+	}
+}
+
+func (rer *Renderer) beginFrame(l *contraption.RenderOp) {
+	rer.ip.BeginFrame(1)
+	rer.page.Reset()
+	// Exact size of the page is set by (*World).Page.
+	rer.w, rer.h = l.Args[1], l.Args[2]
+	if rer.w <= 0 || rer.h <= 0 {
+		rer.w, rer.h = float64(l.Iargs[0]), float64(l.Iargs[1])
+	}
+	// Frame coordinates have y axis pointing down.
+	fmt.Fprintf(&rer.page, "1 0 0 -1 0 %s cm\n", num64(rer.h))
+}
+
+func (rer *Renderer) endFrame() {
+	contents := rer.add(stream(``, rer.page.Bytes()))
+	rer.pages = append(rer.pages, rer.add([]byte(fmt.Sprintf(
+		`<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %d 0 R /Contents %d 0 R >>`,
+		rer.pagesid, num64(rer.w), num64(rer.h), rer.resid, contents))))
+}
+
+/* Objects */
+
+func (rer *Renderer) reserve() int {
+	rer.objs = append(rer.objs, nil)
+	return len(rer.objs)
+}
+
+func (rer *Renderer) add(body []byte) int {
+	rer.objs = append(rer.objs, body)
+	return len(rer.objs)
+}
+
+// resource adds the object to the page resources, reusing the equal ones, and returns its name.
+func (rer *Renderer) resource(kind int, prefix string, body []byte) string {
+	k := prefix + string(body)
+	if name, ok := rer.resources[k]; ok {
+		return name
+	}
+	name := rer.name(kind, prefix, rer.add(body))
+	rer.resources[k] = name
+	return name
+}
+
+func (rer *Renderer) name(kind int, prefix string, id int) string {
+	name := prefix + strconv.Itoa(len(rer.res[kind])+1)
+	rer.res[kind] = append(rer.res[kind], fmt.Sprintf(`/%s %d 0 R`, name, id))
+	return name
+}
+
+func stream(dict string, data []byte) []byte {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(data)
+	zw.Close()
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<< %s/Length %d /Filter /FlateDecode >>\nstream\n", dict, z.Len())
+	b.Write(z.Bytes())
+	b.WriteString("\nendstream")
+	return b.Bytes()
+}
+
+// WriteTo writes the document with all pages rendered so far.
+// Renderer can be used after that, so the next call writes the document with the new pages.
+func (rer *Renderer) WriteTo(w io.Writer) (int64, error) {
+	objs := slices.Clone(rer.objs)
+	add := func(body []byte) int {
+		objs = append(objs, body)
+		return len(objs)
+	}
+	set := func(id int, body string) {
+		objs[id-1] = []byte(body)
+	}
+
+	for _, h := range sortedkeys(rer.fonts) {
+		pf := rer.fonts[h]
+		desc, widths, tounicode := rer.fontobjs(pf)
+		file := add(stream(fmt.Sprintf(`/Length1 %d `, len(pf.f.Data)), pf.f.Data))
+		descid := add([]byte(strings.Replace(desc, `/FontFile2 0 0 R`, fmt.Sprintf(`/FontFile2 %d 0 R`, file), 1)))
+		cid := add([]byte(fmt.Sprintf(`<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s`+
+			` /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >>`+
+			` /FontDescriptor %d 0 R /DW 0 /W %s /CIDToGIDMap /Identity >>`, pf.name, descid, widths)))
+		tu := add(stream(``, tounicode))
+		set(pf.id, fmt.Sprintf(`<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>`,
+			pf.name, cid, tu))
+	}
+
+	var res strings.Builder
+	res.WriteString(`<< /ProcSet [/PDF /Text /ImageB /ImageC]`)
+	for k, names := range rer.res {
+		if len(names) > 0 {
+			fmt.Fprintf(&res, ` %s << %s >>`, resKinds[k], strings.Join(names, ` `))
+		}
+	}
+	res.WriteString(` >>`)
+	set(rer.resid, res.String())
+
+	kids := make([]string, len(rer.pages))
+	for i, id := range rer.pages {
+		kids[i] = fmt.Sprintf(`%d 0 R`, id)
+	}
+	set(rer.pagesid, fmt.Sprintf(`<< /Type /Pages /Kids [%s] /Count %d >>`, strings.Join(kids, ` `), len(rer.pages)))
+	catalog := add([]byte(fmt.Sprintf(`<< /Type /Catalog /Pages %d 0 R >>`, rer.pagesid)))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objs))
+	for i, body := range objs {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		b.Write(body)
+		b.WriteString("\nendobj\n")
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, catalog, xref)
+
+	n, err := w.Write(b.Bytes())
+	return int64(n), err
+}
+
+func sortedkeys[V any](m map[int]V) []int {
+	ks := make([]int, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	slices.Sort(ks)
+	return ks
+}
+
+/* Operands */
+
+func num(x float32) string {
+	return strconv.FormatFloat(float64(x), 'f', -1, 32)
+}
+
+func num64(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+func matrix(t nanovgo.TransformMatrix) string {
+	return fmt.Sprintf(`%s %s %s %s %s %s`, num(t[0]), num(t[1]), num(t[2]), num(t[3]), num(t[4]), num(t[5]))
+}
+
+func rgb(c nanovgo.Color) string {
+	u := func(x float32) string { return num(max(0, min(1, x))) }
+	return fmt.Sprintf(`%s %s %s`, u(c.R), u(c.G), u(c.B))
+}
+
+// alpha sets the constant opacity for both filling and stroking.
+func (rer *Renderer) alpha(a float32) {
+	a = max(0, min(1, a))
+	if a == 1 {
+		return
+	}
+	name := rer.resource(resExtGState, `G`, []byte(fmt.Sprintf(`<< /Type /ExtGState /ca %s /CA %s >>`, num(a), num(a))))
+	fmt.Fprintf(&rer.page, "/%s gs\n", name)
+}
+
+// paint sets the color of filling or stroking.
+func (rer *Renderer) paint(p interp.Paint, alpha float32, stroke bool) {
+	b := &rer.page
+	col, cs, scn := `rg`, `cs`, `scn`
+	if stroke {
+		col, cs, scn = `RG`, `CS`, `SCN`
+	}
+	if p.Solid() || p.Image != 0 {
+		rer.alpha(p.Inner.A * alpha)
+		fmt.Fprintf(b, "%s %s\n", rgb(p.Inner), col)
+		return
+	}
+
+	var shading string
+	fn := fmt.Sprintf(`<< /FunctionType 2 /Domain [0 1] /C0 [%s] /C1 [%s] /N 1 >>`, rgb(p.Inner), rgb(p.Outer))
+	if ok, y0, y1 := p.Linear(); ok {
+		shading = fmt.Sprintf(`<< /ShadingType 2 /ColorSpace /DeviceRGB /Coords [0 %s 0 %s] /Function %s /Extend [true true] >>`,
+			num(y0), num(y1), fn)
+	} else {
+		ok, r0, r1 := p.Radial()
+		if !ok {
+			// Box gradients have no counterpart in PDF, approximate them with the circle.
+			e := max(p.Extent[0], p.Extent[1])
+			r0, r1 = max(0, e-p.Feather*0.5), e+p.Feather*0.5
+		}
+		shading = fmt.Sprintf(`<< /ShadingType 3 /ColorSpace /DeviceRGB /Coords [0 0 %s 0 0 %s] /Function %s /Extend [true true] >>`,
+			num(r0), num(r1), fn)
+	}
+	sh, ok := rer.shadings[shading]
+	if !ok {
+		sh = rer.add([]byte(shading))
+		rer.shadings[shading] = sh
+	}
+	// Pattern space is relative to the default space of the page, not to the current one.
+	flip := nanovgo.TransformMatrix{1, 0, 0, -1, 0, float32(rer.h)}
+	name := rer.resource(resPattern, `P`, []byte(fmt.Sprintf(`<< /PatternType 2 /Shading %d 0 R /Matrix [%s] >>`,
+		sh, matrix(p.Xform.Multiply(flip)))))
+	// Shadings are opaque, so the gradient of opacity is averaged.
+	rer.alpha((p.Inner.A + p.Outer.A) * 0.5 * alpha)
+	fmt.Fprintf(b, "/Pattern %s /%s %s\n", cs, name, scn)
+}
+
+// clip intersects the clipping path with the current scissor.
+func (rer *Renderer) clip() {
+	s := rer.ip.State()
+	if !s.Scissored() {
+		return
+	}
+	ex, ey := s.ScissorExt[0], s.ScissorExt[1]
+	b := &rer.page
+	for i, p := range [4][2]float32{{-ex, -ey}, {ex, -ey}, {ex, ey}, {-ex, ey}} {
+		x, y := s.Scissor.TransformPoint(p[0], p[1])
+		op := `l`
+		if i == 0 {
+			op = `m`
+		}
+		fmt.Fprintf(b, "%s %s %s\n", num(x), num(y), op)
+	}
+	b.WriteString("h W n\n")
+}
+
+/* Drawing */
+
+func (rer *Renderer) path() {
+	b := &rer.page
+	for i, c := range rer.ip.Path {
+		if c.Op == 'Z' {
+			if i == 0 || rer.ip.Path[i-1].Op != 'Z' {
+				b.WriteString("h\n")
+			}
+			continue
+		}
+		n := 1
+		if c.Op == 'C' {
+			n = 3
+		}
+		for _, p := range c.Pts[:n] {
+			b.WriteString(num(p.X))
+			b.WriteByte(' ')
+			b.WriteString(num(p.Y))
+			b.WriteByte(' ')
+		}
+		b.WriteString(map[byte]string{'M': "m\n", 'L': "l\n", 'C': "c\n"}[c.Op])
+	}
+}
+
+func (rer *Renderer) fill(c *contraption.Context) {
+	if len(rer.ip.Path) == 0 {
+		return
+	}
+	s := rer.ip.State()
+	b := &rer.page
+	b.WriteString("q\n")
+	rer.clip()
+	if p := s.Fill; p.Image != 0 {
+		if name := rer.image(c, p.Image); name != `` {
+			rer.path()
+			b.WriteString("W n\n")
+			rer.alpha(p.Inner.A * s.Alpha)
+			// Image is drawn into the unit square with the first row at the top.
+			fmt.Fprintf(b, "%s cm\n%s 0 0 %s 0 %s cm\n/%s Do\n",
+				matrix(p.Xform), num(p.Extent[0]), num(-p.Extent[1]), num(p.Extent[1]), name)
+		}
+	} else {
+		rer.paint(p, s.Alpha, false)
+		rer.path()
+		b.WriteString("f\n")
+	}
+	b.WriteString("Q\n")
+}
+
+func (rer *Renderer) stroke(c *contraption.Context) {
+	if len(rer.ip.Path) == 0 {
+		return
+	}
+	s := rer.ip.State()
+	b := &rer.page
+	b.WriteString("q\n")
+	rer.clip()
+	rer.paint(s.Stroke, s.Alpha, true)
+	caps := map[nanovgo.LineCap]int{nanovgo.Butt: 0, nanovgo.Round: 1, nanovgo.Square: 2}
+	joins := map[nanovgo.LineCap]int{nanovgo.Miter: 0, nanovgo.Round: 1, nanovgo.Bevel: 2}
+	fmt.Fprintf(b, "%s w %d J %d j %s M\n", num(s.StrokeScale()), caps[s.LineCap], joins[s.LineJoin], num(max(1, s.MiterLimit)))
+	rer.path()
+	b.WriteString("S\nQ\n")
+}
+
+// image returns the name of the image XObject.
+func (rer *Renderer) image(c *contraption.Context, h int) string {
+	if s, ok := rer.images[h]; ok {
+		return s
+	}
+	if h <= 0 || h >= len(c.Images) {
+		return ``
+	}
+	ri := c.Images[h]
+	var m image.Image
+	switch {
+	case ri.Deleted:
+		return ``
+	case ri.Image != nil:
+		m = ri.Image
+	case ri.Data != nil && ri.Wh.X > 0 && ri.Wh.Y > 0:
+		r := image.Rect(0, 0, ri.Wh.X, ri.Wh.Y)
+		if ri.ImageFlags&nanovgo.ImagePreMultiplied != 0 {
+			m = &image.RGBA{Pix: ri.Data, Stride: 4 * ri.Wh.X, Rect: r}
+		} else {
+			m = &image.NRGBA{Pix: ri.Data, Stride: 4 * ri.Wh.X, Rect: r}
+		}
+	default:
+		return ``
+	}
+
+	r := m.Bounds()
+	rgb := make([]byte, 0, 3*r.Dx()*r.Dy())
+	a := make([]byte, 0, r.Dx()*r.Dy())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			a = append(a, c.A)
+		}
+	}
+	dict := fmt.Sprintf(`/Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8 `, r.Dx(), r.Dy())
+	smask := rer.add(stream(dict+`/ColorSpace /DeviceGray `, a))
+	id := rer.add(stream(fmt.Sprintf(`%s/ColorSpace /DeviceRGB /SMask %d 0 R `, dict, smask), rgb))
+	s := rer.name(resXObject, `Im`, id)
+	rer.images[h] = s
+	return s
+}
+
+/* Text */
+
+func (rer *Renderer) font(c *contraption.Context, h int) *pdffont {
+	if f, ok := rer.fonts[h]; ok {
+		return f
+	}
+	if h < 0 || h >= len(c.Fonts) || c.Fonts[h].Deleted {
+		return nil
+	}
+	f, err := contraption.NewFont(nil, c.Fonts[h].Data, ``)
+	if err != nil {
+		rer.fonts[h] = nil
+		return nil
+	}
+	pf := &pdffont{
+		h:      h,
+		name:   rer.fontname(f, h),
+		id:     rer.reserve(),
+		f:      f,
+		runes:  map[sfnt.GlyphIndex]rune{},
+		widths: map[sfnt.GlyphIndex]float64{},
+	}
+	pf.ref = rer.name(resFont, `F`, pf.id)
+	rer.fonts[h] = pf
+	return pf
+}
+
+// fontname returns the PostScript name of the font.
+func (rer *Renderer) fontname(f *contraption.Font, h int) string {
+	s, _ := f.Parsed.Name(&rer.sbuf, sfnt.NameIDPostScript)
+	s = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return -1
+	}, s)
+	if s == `` {
+		s = `ContraptionFont` + strconv.Itoa(h)
+	}
+	return s
+}
+
+// fontobjs returns the font descriptor without the font file, the widths array and the ToUnicode CMap.
+func (rer *Renderer) fontobjs(pf *pdffont) (desc, widths string, tounicode []byte) {
+	const ppem = 1000
+	pt := func(x fixed.Int26_6) string { return strconv.Itoa(x.Round()) }
+	m, _ := pf.f.Parsed.Metrics(&rer.sbuf, fixed.I(ppem), font.HintingNone)
+	bb, _ := pf.f.Parsed.Bounds(&rer.sbuf, fixed.I(ppem), font.HintingNone)
+	capheight := m.CapHeight
+	if capheight == 0 {
+		capheight = m.Ascent
+	}
+	desc = fmt.Sprintf(`<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%s %s %s %s] /ItalicAngle 0`+
+		` /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 0 0 R >>`,
+		pf.name, pt(bb.Min.X), pt(-bb.Max.Y), pt(bb.Max.X), pt(-bb.Min.Y), pt(m.Ascent), pt(-m.Descent), pt(capheight))
+
+	gids := make([]sfnt.GlyphIndex, 0, len(pf.widths))
+	for g := range pf.widths {
+		gids = append(gids, g)
+	}
+	slices.Sort(gids)
+
+	var w strings.Builder
+	w.WriteString(`[`)
+	for _, g := range gids {
+		fmt.Fprintf(&w, `%d [%.0f] `, g, pf.widths[g])
+	}
+	w.WriteString(`]`)
+
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for len(gids) > 0 {
+		// CMap blocks are limited to 100 entries.
+		n := min(100, len(gids))
+		fmt.Fprintf(&b, "%d beginbfchar\n", n)
+		for _, g := range gids[:n] {
+			fmt.Fprintf(&b, "<%04X> <", g)
+			for _, u := range utf16.Encode([]rune{pf.runes[g]}) {
+				fmt.Fprintf(&b, "%04X", u)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+		gids = gids[n:]
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return desc, w.String(), b.Bytes()
+}
+
+func (rer *Renderer) text(c *contraption.Context, l *contraption.RenderOp) {
+	s := rer.ip.State()
+	pf := rer.font(c, s.Font)
+	if pf == nil || len(l.Runes) == 0 {
+		return
+	}
+	// Font size is a cap height in Contraption.
+	em := pf.f.Captoem(float64(s.FontSize))
+	x, y := float32(l.Args[1]), float32(l.Args[2])
+
+	var glyphs strings.Builder
+	for _, r := range l.Runes {
+		g, err := pf.f.Parsed.GlyphIndex(&rer.sbuf, r)
+		if err != nil {
+			g = 0
+		}
+		if _, ok := pf.widths[g]; !ok {
+			pf.widths[g] = pf.f.Advance(r) * 1000
+			pf.runes[g] = r
+		}
+		fmt.Fprintf(&glyphs, `%04X`, g)
+	}
+
+	b := &rer.page
+	b.WriteString("q\n")
+	rer.clip()
+	rer.alpha(s.Fill.Inner.A * s.Alpha)
+	fmt.Fprintf(b, "%s rg\n%s cm\n", rgb(s.Fill.Inner), matrix(s.Xform))
+	// Text space is flipped back, so glyphs are not upside down.
+	fmt.Fprintf(b, "BT /%s %s Tf 1 0 0 -1 %s %s Tm <%s> Tj ET\nQ\n",
+		pf.ref, num64(em), num(x), num(y), glyphs.String())
+}
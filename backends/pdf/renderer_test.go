@@ -0,0 +1,112 @@
+package pdf_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/neputevshina/contraption"
+	"github.com/neputevshina/contraption/backends/headless"
+	"github.com/neputevshina/contraption/backends/pdf"
+	"github.com/neputevshina/contraption/backends/software"
+	"github.com/neputevshina/contraption/nanovgo"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+var (
+	streamre    = regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`)
+	startxrefre = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	mediaboxre  = regexp.MustCompile(`/MediaBox \[0 0 ([\d.]+) ([\d.]+)\]`)
+)
+
+func TestPage(t *testing.T) {
+	wer := headless.New(100, 100, 1)
+	wo := contraption.New(wer, software.New(), contraption.Config{})
+	text := wo.NewText(goregular.TTF)
+	red := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(red, red.Rect, image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	h := wo.Vgo.CreateImageFromGoImage(0, red)
+	grad := nanovgo.LinearGradient(0, 0, 0, 20, nanovgo.RGB(255, 0, 0), nanovgo.RGB(0, 0, 255))
+	black := nanovgo.LinearGradient(0, 0, 1, 1, nanovgo.RGB(0, 0, 0), nanovgo.RGB(0, 0, 0))
+	rer := pdf.New()
+	for i := 0; i < 2 && wo.Next(); i++ {
+		wo.Page(210, 297)
+		wo.Root(wo.Compound(wo.Vfollow(), wo.Crop(), wo.Limit(60, 60),
+			wo.Rectangle(50, 20).Fill(grad),
+			wo.Rectangle(10, 10).Fill(nanovgo.ImagePattern(0, 20, 10, 10, 0, h, 1)),
+			text(10, []rune("Hi")).Fill(black)))
+		wo.Export(rer)
+		wo.Develop()
+	}
+	if rer.Pages() != 2 {
+		t.Fatalf("%d pages, want 2", rer.Pages())
+	}
+	var b bytes.Buffer
+	if _, err := rer.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	doc := b.Bytes()
+
+	// Every object is at the offset written in the cross-reference table.
+	m := startxrefre.FindSubmatch(doc)
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) || m == nil {
+		t.Fatalf("document has no header or trailer")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	lines := strings.Split(string(doc[xref:]), "\n")
+	var n int
+	if _, err := fmt.Sscanf(lines[1], "0 %d", &n); err != nil || lines[0] != "xref" {
+		t.Fatalf("no cross-reference table at %d", xref)
+	}
+	for i := 1; i < n; i++ {
+		off, _ := strconv.Atoi(lines[2+i][:10])
+		if want := fmt.Sprintf("%d 0 obj\n", i); !bytes.HasPrefix(doc[off:], []byte(want)) {
+			t.Errorf("object %d is not at %d", i, off)
+		}
+	}
+
+	boxes := mediaboxre.FindAllSubmatch(doc, -1)
+	if len(boxes) != 2 {
+		t.Fatalf("%d media boxes, want 2", len(boxes))
+	}
+	for _, box := range boxes {
+		w, _ := strconv.ParseFloat(string(box[1]), 64)
+		h, _ := strconv.ParseFloat(string(box[2]), 64)
+		if math.Abs(w-210*72/25.4) > 1e-6 || math.Abs(h-297*72/25.4) > 1e-6 {
+			t.Errorf("page is %g×%g, want A4 in points", w, h)
+		}
+	}
+	for _, s := range []string{"/ShadingType 2", "/Subtype /Image", "/FontFile2", "/Subtype /Type0"} {
+		if !bytes.Contains(doc, []byte(s)) {
+			t.Errorf("document has no %s", s)
+		}
+	}
+
+	// Operators are in compressed page contents.
+	var contents []byte
+	for _, s := range streamre.FindAllSubmatch(doc, -1) {
+		r, err := zlib.NewReader(bytes.NewReader(s[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents = append(contents, data...)
+	}
+	for _, op := range []string{"W n", "/Pattern cs", "Do", "Tj"} {
+		if !bytes.Contains(contents, []byte(op)) {
+			t.Errorf("pages have no %s operator", op)
+		}
+	}
+}
@@ -0,0 +1,701 @@
+// Package software implements a headless Renderer that rasterizes
+// the draw list to an image in memory.
+//
+// It does not need a window, a GPU or cgo, so it can be used for screenshots,
+// for the visual tests and on servers.
+// Renderer follows the semantics of the nanovgo renderer: the same transformation,
+// paint and scissor rules and the same one pixel wide antialiasing.
+package software
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/neputevshina/contraption"
+	"github.com/neputevshina/contraption/backends/interp"
+	"github.com/neputevshina/contraption/nanovgo"
+	"github.com/neputevshina/contraption/op"
+	"golang.org/x/image/vector"
+)
+
+// Renderer draws every frame to Image.
+// Image is reallocated by every BeginFrame if the size of the frame was changed.
+type Renderer struct {
+	// Image contains the result of the last frame.
+	Image *image.RGBA
+	// Background is the color the frame is cleared to. White by default.
+	Background color.Color
+
+	ip    interp.Interp
+	paths []subpath
+
+	ratio   float32
+	tessTol float32
+	distTol float32
+	fringe  float32
+
+	z      vector.Rasterizer
+	mask   []uint8
+	images map[int]image.Image
+}
+
+func New() *Renderer {
+	return &Renderer{
+		Background: color.White,
+	}
+}
+
+type point struct {
+	x, y float32
+}
+
+type subpath struct {
+	pts    []point
+	closed bool
+}
+
+func (rer *Renderer) Run(c *contraption.Context) {
+	for i := range c.Log {
+		l := &c.Log[i]
+		if rer.ip.Step(l) {
+			continue
+		}
+		switch l.Tag {
+		case op.BeginFrame:
+			rer.beginFrame(l.Iargs[0], l.Iargs[1], f32(l.Args[0]))
+		case op.Block:
+			panic(`unimplemented`)
+		case op.CancelFrame:
+			panic(`unimplemented`)
+		case op.CreateFontFromMemory:
+		case op.CreateImageFromGoImage, op.CreateImageRGBA, op.UpdateImage, op.DeleteImage:
+			delete(rer.images, l.Himage)
+		case op.CurrentTransform:
+			panic(`getter, unreachable`)
+		case op.DebugDumpPathCache:
+			panic(`unimplemented`)
+		case op.Delete:
+			panic(`unimplemented`)
+		case op.EndFrame:
+		case op.Fill:
+			rer.fill(c)
+		case op.FindFont:
+			panic(`unimplemented`)
+		case op.FontBlur:
+			panic(`getter, unreachable`)
+		case op.FontFace:
+			panic(`getter, unreachable`)
+		case op.FontFaceID:
+			panic(`getter, unreachable`)
+		case op.FontSize:
+			panic(`getter, unreachable`)
+		case op.GlobalAlpha:
+			panic(`getter, unreachable`)
+		case op.ImageSize:
+			panic(`getter, unreachable`)
+		case op.LineCap:
+			panic(`getter, unreachable`)
+		case op.LineJoin:
+			panic(`getter, unreachable`)
+		case op.MiterLimit:
+			panic(`getter, unreachable`)
+		case op.SetFontBlur:
+		case op.SetFontFace:
+			panic(`unimplemented`)
+		case op.SetTextAlign:
+		case op.SetTextLetterSpacing:
+		case op.SetTextLineHeight:
+		case op.Stroke:
+			rer.stroke(c)
+		case op.StrokeWidth:
+			panic(`getter, unreachable`)
+		case op.TextAlign:
+			panic(`getter, unreachable`)
+		case op.TextBounds:
+			panic(`getter, unreachable`)
+		case op.TextLetterSpacing:
+			panic(`getter, unreachable`)
+		case op.TextLineHeight:
+			panic(`getter, unreachable`)
+		case op.TextMetrics:
+			panic(`getter, unreachable`)
+		case op.TextRune:
+			rer.text(c, l)
+		default:
+			panic(`unreachable`)
+		}
+	}
+}
+
+func (rer *Renderer) beginFrame(w, h int, ratio float32) {
+	if ratio <= 0 {
+		ratio = 1
+	}
+	rer.ratio = ratio
+	rer.tessTol = 0.25 / ratio
+	rer.distTol = 0.01 / ratio
+	rer.fringe = 1 / ratio
+	rer.ip.BeginFrame(ratio)
+
+	rect := image.Rect(0, 0, int(float32(w)*ratio+0.5), int(float32(h)*ratio+0.5))
+	if rer.Image == nil || rer.Image.Rect != rect {
+		rer.Image = image.NewRGBA(rect)
+	}
+	bg := rer.Background
+	if bg == nil {
+		bg = color.Transparent
+	}
+	draw.Draw(rer.Image, rect, image.NewUniform(bg), image.Point{}, draw.Src)
+}
+
+/* Flattening */
+
+func (rer *Renderer) addpoint(x, y float32) {
+	if len(rer.paths) == 0 {
+		rer.paths = append(rer.paths, subpath{})
+	}
+	p := &rer.paths[len(rer.paths)-1]
+	if n := len(p.pts); n > 0 {
+		l := p.pts[n-1]
+		if ptequals(l.x, l.y, x, y, rer.distTol) {
+			return
+		}
+	}
+	p.pts = append(p.pts, point{x, y})
+}
+
+func (rer *Renderer) tesselate(x1, y1, x2, y2, x3, y3, x4, y4 float32, level int) {
+	if level > 10 {
+		return
+	}
+	dx := x4 - x1
+	dy := y4 - y1
+	d2 := absf((x2-x4)*dy - (y2-y4)*dx)
+	d3 := absf((x3-x4)*dy - (y3-y4)*dx)
+	if (d2+d3)*(d2+d3) < rer.tessTol*(dx*dx+dy*dy) {
+		rer.addpoint(x4, y4)
+		return
+	}
+
+	x12 := (x1 + x2) * 0.5
+	y12 := (y1 + y2) * 0.5
+	x23 := (x2 + x3) * 0.5
+	y23 := (y2 + y3) * 0.5
+	x34 := (x3 + x4) * 0.5
+	y34 := (y3 + y4) * 0.5
+	x123 := (x12 + x23) * 0.5
+	y123 := (y12 + y23) * 0.5
+	x234 := (x23 + x34) * 0.5
+	y234 := (y23 + y34) * 0.5
+	x1234 := (x123 + x234) * 0.5
+	y1234 := (y123 + y234) * 0.5
+
+	rer.tesselate(x1, y1, x12, y12, x123, y123, x1234, y1234, level+1)
+	rer.tesselate(x1234, y1234, x234, y234, x34, y34, x4, y4, level+1)
+}
+
+// flatten converts the current path to polylines. Closed subpaths lose the last point
+// if it is the same as the first.
+func (rer *Renderer) flatten() []subpath {
+	rer.paths = rer.paths[:0]
+	var last point
+	for _, c := range rer.ip.Path {
+		switch c.Op {
+		case 'M':
+			rer.paths = append(rer.paths, subpath{})
+			rer.addpoint(c.Pts[0].X, c.Pts[0].Y)
+		case 'L':
+			rer.addpoint(c.Pts[0].X, c.Pts[0].Y)
+		case 'C':
+			if len(rer.paths) > 0 {
+				p := c.Pts
+				rer.tesselate(last.x, last.y, p[0].X, p[0].Y, p[1].X, p[1].Y, p[2].X, p[2].Y, 0)
+			}
+		case 'Z':
+			if len(rer.paths) > 0 {
+				rer.paths[len(rer.paths)-1].closed = true
+			}
+		}
+		if c.Op != 'Z' {
+			l := c.Last()
+			last = point{l.X, l.Y}
+		}
+	}
+	for i := range rer.paths {
+		p := &rer.paths[i]
+		if n := len(p.pts); n > 1 {
+			a, b := p.pts[0], p.pts[n-1]
+			if ptequals(a.x, a.y, b.x, b.y, rer.distTol) {
+				p.pts = p.pts[:n-1]
+				p.closed = true
+			}
+		}
+	}
+	return rer.paths
+}
+
+/* Rasterization */
+
+// shader evaluates a paint at the pixels of the frame.
+// It is the software counterpart of the nanovgo fragment shader.
+type shader struct {
+	interp.Paint
+	inv     nanovgo.TransformMatrix
+	sinv    nanovgo.TransformMatrix
+	sext    [2]float32
+	sscale  [2]float32
+	noclip  bool
+	img     image.Image
+	imgsize point
+	repeatx bool
+	repeaty bool
+	ratio   float32
+}
+
+func (rer *Renderer) shader(c *contraption.Context, p interp.Paint, s *interp.State) *shader {
+	sh := &shader{
+		Paint: p,
+		inv:   p.Xform.Inverse(),
+		ratio: rer.ratio,
+	}
+	sh.Inner.A *= s.Alpha
+	sh.Outer.A *= s.Alpha
+
+	if !s.Scissored() {
+		sh.noclip = true
+	} else {
+		t := s.Scissor
+		sh.sinv = t.Inverse()
+		sh.sext = s.ScissorExt
+		sh.sscale[0] = f32(math.Sqrt(float64(t[0]*t[0]+t[2]*t[2]))) / rer.fringe
+		sh.sscale[1] = f32(math.Sqrt(float64(t[1]*t[1]+t[3]*t[3]))) / rer.fringe
+	}
+
+	if p.Image > 0 && p.Image < len(c.Images) {
+		sh.img = rer.image(c, p.Image)
+		if sh.img != nil {
+			b := sh.img.Bounds()
+			sh.imgsize = point{float32(b.Dx()), float32(b.Dy())}
+			fl := c.Images[p.Image].ImageFlags
+			sh.repeatx = fl&nanovgo.ImageRepeatX != 0
+			sh.repeaty = fl&nanovgo.ImageRepeatY != 0
+		}
+	}
+	return sh
+}
+
+// image returns a Go image for the image handle, converting the raw data if needed.
+func (rer *Renderer) image(c *contraption.Context, h int) image.Image {
+	if m, ok := rer.images[h]; ok {
+		return m
+	}
+	ri := c.Images[h]
+	var m image.Image
+	switch {
+	case ri.Deleted:
+		return nil
+	case ri.Image != nil:
+		m = ri.Image
+	case ri.Data != nil && ri.Wh.X > 0 && ri.Wh.Y > 0:
+		r := image.Rect(0, 0, ri.Wh.X, ri.Wh.Y)
+		if ri.ImageFlags&nanovgo.ImagePreMultiplied != 0 {
+			m = &image.RGBA{Pix: ri.Data, Stride: 4 * ri.Wh.X, Rect: r}
+		} else {
+			m = &image.NRGBA{Pix: ri.Data, Stride: 4 * ri.Wh.X, Rect: r}
+		}
+	default:
+		return nil
+	}
+	if rer.images == nil {
+		rer.images = map[int]image.Image{}
+	}
+	rer.images[h] = m
+	return m
+}
+
+func (sh *shader) clip(x, y float32) float32 {
+	if sh.noclip {
+		return 1
+	}
+	px, py := sh.sinv.TransformPoint(x, y)
+	cx := 0.5 - (absf(px)-sh.sext[0])*sh.sscale[0]
+	cy := 0.5 - (absf(py)-sh.sext[1])*sh.sscale[1]
+	return clampf(cx, 0, 1) * clampf(cy, 0, 1)
+}
+
+// at returns a premultiplied color of the paint at the frame pixel x, y.
+func (sh *shader) at(x, y int) (r, g, b, a float32) {
+	ux := (float32(x) + 0.5) / sh.ratio
+	uy := (float32(y) + 0.5) / sh.ratio
+	k := sh.clip(ux, uy)
+	if k == 0 {
+		return
+	}
+	px, py := sh.inv.TransformPoint(ux, uy)
+
+	if sh.img != nil {
+		r, g, b, a = sh.texel(px/sh.Extent[0], py/sh.Extent[1])
+		c := sh.Inner
+		k *= c.A
+		return r * c.R * k, g * c.G * k, b * c.B * k, a * k
+	}
+
+	d := clampf((sdroundrect(px, py, sh.Extent[0], sh.Extent[1], sh.Radius)+sh.Feather*0.5)/sh.Feather, 0, 1)
+	ic, oc := sh.Inner, sh.Outer
+	ia, oa := ic.A*(1-d), oc.A*d
+	r = ic.R*ia + oc.R*oa
+	g = ic.G*ia + oc.G*oa
+	b = ic.B*ia + oc.B*oa
+	a = ia + oa
+	return r * k, g * k, b * k, a * k
+}
+
+// texel samples the image at the normalized coordinates with bilinear filtering.
+func (sh *shader) texel(u, v float32) (r, g, b, a float32) {
+	w, h := sh.imgsize.x, sh.imgsize.y
+	if w == 0 || h == 0 {
+		return
+	}
+	fx := u*w - 0.5
+	fy := v*h - 0.5
+	x0 := float32(math.Floor(float64(fx)))
+	y0 := float32(math.Floor(float64(fy)))
+	tx, ty := fx-x0, fy-y0
+
+	o := sh.img.Bounds().Min
+	at := func(x, y float32) (r, g, b, a float32) {
+		ix := wrap(int(x), int(w), sh.repeatx)
+		iy := wrap(int(y), int(h), sh.repeaty)
+		cr, cg, cb, ca := sh.img.At(o.X+ix, o.Y+iy).RGBA()
+		return float32(cr) / 0xffff, float32(cg) / 0xffff, float32(cb) / 0xffff, float32(ca) / 0xffff
+	}
+	r00, g00, b00, a00 := at(x0, y0)
+	r10, g10, b10, a10 := at(x0+1, y0)
+	r01, g01, b01, a01 := at(x0, y0+1)
+	r11, g11, b11, a11 := at(x0+1, y0+1)
+	lerp2 := func(c00, c10, c01, c11 float32) float32 {
+		return (c00*(1-tx)+c10*tx)*(1-ty) + (c01*(1-tx)+c11*tx)*ty
+	}
+	return lerp2(r00, r10, r01, r11), lerp2(g00, g10, g01, g11), lerp2(b00, b10, b01, b11), lerp2(a00, a10, a01, a11)
+}
+
+func wrap(i, n int, repeat bool) int {
+	if repeat {
+		i %= n
+		if i < 0 {
+			i += n
+		}
+		return i
+	}
+	return max(0, min(n-1, i))
+}
+
+// blend composes a premultiplied color over the frame pixel.
+func (rer *Renderer) blend(x, y int, r, g, b, a float32) {
+	if a <= 0 {
+		return
+	}
+	m := rer.Image
+	i := m.PixOffset(x, y)
+	px := m.Pix[i : i+4 : i+4]
+	k := 1 - a
+	px[0] = u8(r*255 + float32(px[0])*k)
+	px[1] = u8(g*255 + float32(px[1])*k)
+	px[2] = u8(b*255 + float32(px[2])*k)
+	px[3] = u8(a*255 + float32(px[3])*k)
+}
+
+// raster rasterizes polygons given in user space with the nonzero rule and composes
+// the paint through the coverage mask.
+func (rer *Renderer) raster(polys [][]point, sh *shader) {
+	if rer.Image == nil {
+		return
+	}
+	minx, miny := float32(math.Inf(1)), float32(math.Inf(1))
+	maxx, maxy := float32(math.Inf(-1)), float32(math.Inf(-1))
+	for _, p := range polys {
+		for _, q := range p {
+			minx, miny = min(minx, q.x), min(miny, q.y)
+			maxx, maxy = max(maxx, q.x), max(maxy, q.y)
+		}
+	}
+	k := rer.ratio
+	bounds := image.Rect(
+		int(math.Floor(float64(minx*k))), int(math.Floor(float64(miny*k))),
+		int(math.Ceil(float64(maxx*k))), int(math.Ceil(float64(maxy*k))),
+	).Intersect(rer.Image.Rect)
+	if bounds.Empty() {
+		return
+	}
+
+	w, h := bounds.Dx(), bounds.Dy()
+	rer.z.Reset(w, h)
+	rer.z.DrawOp = draw.Src
+	ox, oy := float32(bounds.Min.X), float32(bounds.Min.Y)
+	for _, p := range polys {
+		if len(p) < 3 {
+			continue
+		}
+		rer.z.MoveTo(p[0].x*k-ox, p[0].y*k-oy)
+		for _, q := range p[1:] {
+			rer.z.LineTo(q.x*k-ox, q.y*k-oy)
+		}
+		rer.z.ClosePath()
+	}
+	// The rasterizer expects the mask without gaps between rows.
+	if cap(rer.mask) < w*h {
+		rer.mask = make([]uint8, w*h)
+	}
+	mask := &image.Alpha{Pix: rer.mask[:w*h], Stride: w, Rect: image.Rect(0, 0, w, h)}
+	rer.z.Draw(mask, mask.Rect, image.Opaque, image.Point{})
+
+	for y := 0; y < h; y++ {
+		row := mask.Pix[y*mask.Stride : y*mask.Stride+w]
+		for x, cov := range row {
+			if cov == 0 {
+				continue
+			}
+			fx, fy := bounds.Min.X+x, bounds.Min.Y+y
+			r, g, b, a := sh.at(fx, fy)
+			f := float32(cov) / 255
+			rer.blend(fx, fy, r*f, g*f, b*f, a*f)
+		}
+	}
+}
+
+func (rer *Renderer) fill(c *contraption.Context) {
+	s := rer.ip.State()
+	polys := [][]point{}
+	for _, p := range rer.flatten() {
+		if len(p.pts) > 2 {
+			polys = append(polys, p.pts)
+		}
+	}
+	rer.raster(polys, rer.shader(c, s.Fill, s))
+}
+
+func (rer *Renderer) stroke(c *contraption.Context) {
+	s := rer.ip.State()
+	w := s.StrokeScale()
+	p := s.Stroke
+	if w < rer.fringe {
+		// If the stroke width is less than pixel size, use alpha to emulate coverage.
+		// Since coverage is area, scale by alpha*alpha.
+		a := clampf(w/rer.fringe, 0, 1)
+		p.Inner.A *= a * a
+		p.Outer.A *= a * a
+		w = rer.fringe
+	}
+
+	polys := [][]point{}
+	for _, sp := range rer.flatten() {
+		polys = rer.expandstroke(polys, sp, w*0.5, s)
+	}
+	rer.raster(polys, rer.shader(c, p, s))
+}
+
+// expandstroke converts a subpath to the set of positively oriented polygons
+// that cover the stroke. Overlapping polygons are united by the nonzero rule.
+func (rer *Renderer) expandstroke(polys [][]point, sp subpath, hw float32, s *interp.State) [][]point {
+	pts := sp.pts
+	n := len(pts)
+	if n < 2 {
+		return polys
+	}
+	add := func(poly ...point) {
+		if area(poly) < 0 {
+			for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+				poly[i], poly[j] = poly[j], poly[i]
+			}
+		}
+		polys = append(polys, poly)
+	}
+	ncap := curvedivs(hw, math.Pi, rer.tessTol)
+	circle := func(c point) {
+		poly := make([]point, 0, 2*ncap)
+		for i := 0; i < 2*ncap; i++ {
+			a := float64(i) / float64(2*ncap) * 2 * math.Pi
+			poly = append(poly, point{c.x + hw*f32(math.Cos(a)), c.y + hw*f32(math.Sin(a))})
+		}
+		add(poly...)
+	}
+
+	nseg := n - 1
+	if sp.closed {
+		nseg = n
+	}
+	for i := 0; i < nseg; i++ {
+		p0, p1 := pts[i], pts[(i+1)%n]
+		dx, dy := normalize(p1.x-p0.x, p1.y-p0.y)
+		nx, ny := -dy*hw, dx*hw
+		add(point{p0.x + nx, p0.y + ny}, point{p1.x + nx, p1.y + ny}, point{p1.x - nx, p1.y - ny}, point{p0.x - nx, p0.y - ny})
+	}
+
+	// Joins.
+	for i := 0; i < n; i++ {
+		if !sp.closed && (i == 0 || i == n-1) {
+			continue
+		}
+		p0, p1, p2 := pts[(i+n-1)%n], pts[i], pts[(i+1)%n]
+		d0x, d0y := normalize(p1.x-p0.x, p1.y-p0.y)
+		d1x, d1y := normalize(p2.x-p1.x, p2.y-p1.y)
+		cross := d0x*d1y - d0y*d1x
+		if absf(cross) < 1e-6 && d0x*d1x+d0y*d1y > 0 {
+			continue
+		}
+		if s.LineJoin == nanovgo.Round {
+			circle(p1)
+			continue
+		}
+		side := float32(1)
+		if cross > 0 {
+			side = -1
+		}
+		n0x, n0y := -d0y*side, d0x*side
+		n1x, n1y := -d1y*side, d1x*side
+		a := point{p1.x + n0x*hw, p1.y + n0y*hw}
+		b := point{p1.x + n1x*hw, p1.y + n1y*hw}
+		dmx, dmy := (n0x+n1x)*0.5, (n0y+n1y)*0.5
+		dmr2 := dmx*dmx + dmy*dmy
+		if s.LineJoin == nanovgo.Miter && dmr2 > 1e-6 && dmr2*s.MiterLimit*s.MiterLimit >= 1 {
+			m := point{p1.x + dmx/dmr2*hw, p1.y + dmy/dmr2*hw}
+			add(p1, a, m, b)
+		} else {
+			add(p1, a, b)
+		}
+	}
+
+	// Caps.
+	if !sp.closed {
+		for _, e := range [2][2]point{{pts[1], pts[0]}, {pts[n-2], pts[n-1]}} {
+			dx, dy := normalize(e[1].x-e[0].x, e[1].y-e[0].y)
+			p := e[1]
+			switch s.LineCap {
+			case nanovgo.Round:
+				circle(p)
+			case nanovgo.Square:
+				nx, ny := -dy*hw, dx*hw
+				ex, ey := dx*hw, dy*hw
+				add(point{p.x + nx, p.y + ny}, point{p.x + nx + ex, p.y + ny + ey}, point{p.x - nx + ex, p.y - ny + ey}, point{p.x - nx, p.y - ny})
+			}
+		}
+	}
+	return polys
+}
+
+/* Text */
+
+func (rer *Renderer) text(c *contraption.Context, l *contraption.RenderOp) {
+	if rer.Image == nil {
+		return
+	}
+	s := rer.ip.State()
+	atlas, aw, ah := c.Fs.GetTextureData()
+	if aw == 0 || ah == 0 {
+		return
+	}
+	sh := rer.shader(c, s.Fill, s)
+	col := sh.Inner
+	invScale := f32(l.Args[0])
+	t := s.Xform
+	k := rer.ratio
+
+	for _, q := range c.SpriteUnits[l.Left:l.Right] {
+		if q.Clip.Dx() == 0 || q.Clip.Dy() == 0 {
+			continue
+		}
+		// Corners of the glyph quad in user space, the quad is a parallelogram.
+		x0, y0 := t.TransformPoint(f32(q.Clip.Min.X)*invScale, f32(q.Clip.Min.Y)*invScale)
+		x1, y1 := t.TransformPoint(f32(q.Clip.Max.X)*invScale, f32(q.Clip.Min.Y)*invScale)
+		x3, y3 := t.TransformPoint(f32(q.Clip.Min.X)*invScale, f32(q.Clip.Max.Y)*invScale)
+		ux, uy := x1-x0, y1-y0
+		vx, vy := x3-x0, y3-y0
+		det := ux*vy - uy*vx
+		if absf(det) < 1e-9 {
+			continue
+		}
+		x2, y2 := x0+ux+vx, y0+uy+vy
+
+		bounds := image.Rect(
+			int(math.Floor(float64(min(x0, x1, x2, x3)*k))), int(math.Floor(float64(min(y0, y1, y2, y3)*k))),
+			int(math.Ceil(float64(max(x0, x1, x2, x3)*k))), int(math.Ceil(float64(max(y0, y1, y2, y3)*k))),
+		).Intersect(rer.Image.Rect)
+
+		for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+			for px := bounds.Min.X; px < bounds.Max.X; px++ {
+				fx := (float32(px)+0.5)/k - x0
+				fy := (float32(py)+0.5)/k - y0
+				u := (fx*vy - fy*vx) / det
+				v := (ux*fy - uy*fx) / det
+				if u < 0 || u >= 1 || v < 0 || v >= 1 {
+					continue
+				}
+				tx := int((f32(q.Tc.Min.X) + u*f32(q.Tc.Dx())) * float32(aw))
+				ty := int((f32(q.Tc.Min.Y) + v*f32(q.Tc.Dy())) * float32(ah))
+				tx = max(0, min(aw-1, tx))
+				ty = max(0, min(ah-1, ty))
+				cov := float32(atlas[ty*aw+tx]) / 255
+				if cov == 0 {
+					continue
+				}
+				cov *= sh.clip((float32(px)+0.5)/k, (float32(py)+0.5)/k) * col.A
+				rer.blend(px, py, col.R*cov, col.G*cov, col.B*cov, cov)
+			}
+		}
+	}
+}
+
+/* Math */
+
+func f32(x float64) float32 { return float32(x) }
+
+func u8(x float32) uint8 {
+	return uint8(clampf(x+0.5, 0, 255))
+}
+
+func absf(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func clampf(x, a, b float32) float32 {
+	return max(a, min(b, x))
+}
+
+func normalize(x, y float32) (float32, float32) {
+	d := f32(math.Sqrt(float64(x*x + y*y)))
+	if d > 1e-6 {
+		return x / d, y / d
+	}
+	return x, y
+}
+
+func ptequals(x1, y1, x2, y2, tol float32) bool {
+	dx := x2 - x1
+	dy := y2 - y1
+	return dx*dx+dy*dy < tol*tol
+}
+
+func sdroundrect(x, y, ex, ey, rad float32) float32 {
+	dx := absf(x) - (ex - rad)
+	dy := absf(y) - (ey - rad)
+	l := f32(math.Hypot(float64(max(dx, 0)), float64(max(dy, 0))))
+	return min(max(dx, dy), 0) + l - rad
+}
+
+func area(poly []point) (a float32) {
+	for i := range poly {
+		p, q := poly[i], poly[(i+1)%len(poly)]
+		a += p.x*q.y - q.x*p.y
+	}
+	return a / 2
+}
+
+func curvedivs(r, arc, tol float32) int {
+	da := math.Acos(float64(r/(r+tol))) * 2
+	return max(2, int(math.Ceil(float64(arc)/da)))
+}
@@ -0,0 +1,393 @@
+// Package svg implements a Renderer that writes every frame as an SVG document.
+//
+// Paths are written in the window coordinates, so the document looks exactly as the window.
+// Solid paints become colors, linear gradients become <linearGradient>,
+// other gradients are approximated by <radialGradient> and image patterns become
+// <pattern> with embedded PNG image. Scissors become <clipPath>.
+// Text is written as <text> with fonts embedded to the document or, if Outline is set,
+// as paths made of glyph outlines.
+//
+// Renderer can be used as the main renderer of a World, or for exporting a single frame
+// with (*World).Export.
+package svg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"strconv"
+
+	"github.com/neputevshina/contraption"
+	"github.com/neputevshina/contraption/backends/interp"
+	"github.com/neputevshina/contraption/nanovgo"
+	"github.com/neputevshina/contraption/op"
+)
+
+type Renderer struct {
+	// Out receives the document of every frame if not nil.
+	Out io.Writer
+	// Document contains the document of the last frame.
+	Document []byte
+	// Outline makes text to be written as glyph outlines instead of <text>.
+	Outline bool
+
+	ip  interp.Interp
+	buf bytes.Buffer
+	ids int
+
+	clips     map[clipkey]string
+	usedfonts []int
+	fonts     map[int]*contraption.Font
+	images    map[int]string
+}
+
+type clipkey struct {
+	xform nanovgo.TransformMatrix
+	ext   [2]float32
+}
+
+func New(out io.Writer) *Renderer {
+	return &Renderer{
+		Out:    out,
+		fonts:  map[int]*contraption.Font{},
+		images: map[int]string{},
+	}
+}
+
+func (rer *Renderer) Run(c *contraption.Context) {
+	for i := range c.Log {
+		l := &c.Log[i]
+		if rer.ip.Step(l) {
+			continue
+		}
+		switch l.Tag {
+		case op.BeginFrame:
+			rer.beginFrame(l.Iargs[0], l.Iargs[1])
+		case op.EndFrame:
+			rer.endFrame(c)
+		case op.Block:
+			panic(`unimplemented`)
+		case op.CancelFrame:
+			panic(`unimplemented`)
+		case op.CreateFontFromMemory:
+		case op.CreateImageFromGoImage, op.CreateImageRGBA, op.UpdateImage, op.DeleteImage:
+			delete(rer.images, l.Himage)
+		case op.Fill:
+			rer.fill(c)
+		case op.Stroke:
+			rer.stroke(c)
+		case op.TextRune:
+			rer.text(c, l)
+		case op.SetFontBlur, op.SetTextAlign, op.SetTextLetterSpacing, op.SetTextLineHeight:
+		case op.SetFontFace, op.DebugDumpPathCache, op.Delete, op.FindFont:
+			panic(`unimplemented`)
+		case op.CurrentTransform, op.FontBlur, op.FontFace, op.FontFaceID, op.FontSize, op.GlobalAlpha,
+			op.ImageSize, op.LineCap, op.LineJoin, op.MiterLimit, op.StrokeWidth, op.TextAlign,
+			op.TextBounds, op.TextLetterSpacing, op.TextLineHeight, op.TextMetrics:
+			panic(`getter, unreachable`)
+		default:
+			panic(`unreachable`)
+		}
+	}
+}
+
+func (rer *Renderer) beginFrame(w, h int) {
+	rer.ip.BeginFrame(1)
+	rer.buf.Reset()
+	rer.ids = 0
+	rer.clips = map[clipkey]string{}
+	rer.usedfonts = rer.usedfonts[:0]
+	fmt.Fprintf(&rer.buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", w, h, w, h)
+}
+
+func (rer *Renderer) endFrame(c *contraption.Context) {
+	b := &rer.buf
+	if len(rer.usedfonts) > 0 {
+		b.WriteString("<style>\n")
+		for _, h := range rer.usedfonts {
+			fmt.Fprintf(b, "@font-face { font-family: %s; src: url(data:font/ttf;base64,%s); }\n",
+				fontfamily(h), base64.StdEncoding.EncodeToString(c.Fonts[h].Data))
+		}
+		b.WriteString("</style>\n")
+	}
+	b.WriteString("</svg>\n")
+	rer.Document = append(rer.Document[:0], b.Bytes()...)
+	if rer.Out != nil {
+		_, err := rer.Out.Write(rer.Document)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (rer *Renderer) id(prefix string) string {
+	rer.ids++
+	return prefix + strconv.Itoa(rer.ids)
+}
+
+/* Attributes */
+
+func num(x float32) string {
+	return strconv.FormatFloat(float64(x), 'f', -1, 32)
+}
+
+func matrix(t nanovgo.TransformMatrix) string {
+	return fmt.Sprintf(`matrix(%s %s %s %s %s %s)`, num(t[0]), num(t[1]), num(t[2]), num(t[3]), num(t[4]), num(t[5]))
+}
+
+func rgb(c nanovgo.Color) string {
+	u8 := func(x float32) int { return int(max(0, min(1, x))*255 + 0.5) }
+	return fmt.Sprintf(`#%02x%02x%02x`, u8(c.R), u8(c.G), u8(c.B))
+}
+
+// paint writes the definitions needed for the paint and returns the attributes of it,
+// like fill="#ffffff" fill-opacity="0.5".
+func (rer *Renderer) paint(c *contraption.Context, attr string, p interp.Paint, alpha float32) string {
+	b := &rer.buf
+	if p.Solid() {
+		return fmt.Sprintf(`%s="%s" %s-opacity="%s"`, attr, rgb(p.Inner), attr, num(p.Inner.A*alpha))
+	}
+	stop := func(offset float32, c nanovgo.Color) {
+		fmt.Fprintf(b, `<stop offset="%s" stop-color="%s" stop-opacity="%s"/>`, num(offset), rgb(c), num(c.A*alpha))
+	}
+
+	id := rer.id(`p`)
+	if p.Image != 0 {
+		href := rer.image(c, p.Image)
+		if href == `` {
+			return attr + `="none"`
+		}
+		fmt.Fprintf(b, `<pattern id="%s" patternUnits="userSpaceOnUse" width="%s" height="%s" patternTransform="%s">`,
+			id, num(p.Extent[0]), num(p.Extent[1]), matrix(p.Xform))
+		fmt.Fprintf(b, `<image width="%s" height="%s" preserveAspectRatio="none" opacity="%s" xlink:href="%s"/>`,
+			num(p.Extent[0]), num(p.Extent[1]), num(p.Inner.A*alpha), href)
+		b.WriteString("</pattern>\n")
+	} else if ok, y0, y1 := p.Linear(); ok {
+		fmt.Fprintf(b, `<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="0" y1="%s" x2="0" y2="%s" gradientTransform="%s">`,
+			id, num(y0), num(y1), matrix(p.Xform))
+		stop(0, p.Inner)
+		stop(1, p.Outer)
+		b.WriteString("</linearGradient>\n")
+	} else {
+		ok, r0, r1 := p.Radial()
+		if !ok {
+			// Box gradients have no counterpart in SVG, approximate them with the circle.
+			e := max(p.Extent[0], p.Extent[1])
+			r0, r1 = max(0, e-p.Feather*0.5), e+p.Feather*0.5
+		}
+		fmt.Fprintf(b, `<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="0" cy="0" r="%s" gradientTransform="%s">`,
+			id, num(r1), matrix(p.Xform))
+		stop(r0/r1, p.Inner)
+		stop(1, p.Outer)
+		b.WriteString("</radialGradient>\n")
+	}
+	return fmt.Sprintf(`%s="url(#%s)"`, attr, id)
+}
+
+// image returns the data URL of the image.
+func (rer *Renderer) image(c *contraption.Context, h int) string {
+	if s, ok := rer.images[h]; ok {
+		return s
+	}
+	if h <= 0 || h >= len(c.Images) {
+		return ``
+	}
+	ri := c.Images[h]
+	var m image.Image
+	switch {
+	case ri.Deleted:
+		return ``
+	case ri.Image != nil:
+		m = ri.Image
+	case ri.Data != nil && ri.Wh.X > 0 && ri.Wh.Y > 0:
+		r := image.Rect(0, 0, ri.Wh.X, ri.Wh.Y)
+		if ri.ImageFlags&nanovgo.ImagePreMultiplied != 0 {
+			m = &image.RGBA{Pix: ri.Data, Stride: 4 * ri.Wh.X, Rect: r}
+		} else {
+			m = &image.NRGBA{Pix: ri.Data, Stride: 4 * ri.Wh.X, Rect: r}
+		}
+	default:
+		return ``
+	}
+	var b bytes.Buffer
+	err := png.Encode(&b, m)
+	if err != nil {
+		panic(err)
+	}
+	s := `data:image/png;base64,` + base64.StdEncoding.EncodeToString(b.Bytes())
+	rer.images[h] = s
+	return s
+}
+
+// clip writes the clip path for the current scissor and returns the attribute for it.
+func (rer *Renderer) clip() string {
+	s := rer.ip.State()
+	if !s.Scissored() {
+		return ``
+	}
+	k := clipkey{s.Scissor, s.ScissorExt}
+	id, ok := rer.clips[k]
+	if !ok {
+		id = rer.id(`c`)
+		rer.clips[k] = id
+		ex, ey := s.ScissorExt[0], s.ScissorExt[1]
+		fmt.Fprintf(&rer.buf, `<clipPath id="%s" clipPathUnits="userSpaceOnUse"><rect x="%s" y="%s" width="%s" height="%s" transform="%s"/></clipPath>`+"\n",
+			id, num(-ex), num(-ey), num(2*ex), num(2*ey), matrix(s.Scissor))
+	}
+	return fmt.Sprintf(` clip-path="url(#%s)"`, id)
+}
+
+/* Drawing */
+
+func pathdata(path []interp.Cmd) string {
+	var b bytes.Buffer
+	for i, c := range path {
+		if c.Op == 'Z' && i > 0 && path[i-1].Op == 'Z' {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteByte(c.Op)
+		n := 1
+		switch c.Op {
+		case 'Z':
+			n = 0
+		case 'C':
+			n = 3
+		}
+		for _, p := range c.Pts[:n] {
+			b.WriteByte(' ')
+			b.WriteString(num(p.X))
+			b.WriteByte(' ')
+			b.WriteString(num(p.Y))
+		}
+	}
+	return b.String()
+}
+
+func (rer *Renderer) fill(c *contraption.Context) {
+	if len(rer.ip.Path) == 0 {
+		return
+	}
+	s := rer.ip.State()
+	clip := rer.clip()
+	paint := rer.paint(c, `fill`, s.Fill, s.Alpha)
+	fmt.Fprintf(&rer.buf, `<path d="%s" %s%s/>`+"\n", pathdata(rer.ip.Path), paint, clip)
+}
+
+func (rer *Renderer) stroke(c *contraption.Context) {
+	if len(rer.ip.Path) == 0 {
+		return
+	}
+	s := rer.ip.State()
+	clip := rer.clip()
+	paint := rer.paint(c, `stroke`, s.Stroke, s.Alpha)
+	caps := map[nanovgo.LineCap]string{nanovgo.Butt: `butt`, nanovgo.Round: `round`, nanovgo.Square: `square`}
+	joins := map[nanovgo.LineCap]string{nanovgo.Miter: `miter`, nanovgo.Round: `round`, nanovgo.Bevel: `bevel`}
+	fmt.Fprintf(&rer.buf, `<path d="%s" fill="none" %s stroke-width="%s" stroke-linecap="%s" stroke-linejoin="%s" stroke-miterlimit="%s"%s/>`+"\n",
+		pathdata(rer.ip.Path), paint, num(s.StrokeScale()), caps[s.LineCap], joins[s.LineJoin], num(max(1, s.MiterLimit)), clip)
+}
+
+/* Text */
+
+func fontfamily(h int) string {
+	return `contraption-font-` + strconv.Itoa(h)
+}
+
+func (rer *Renderer) font(c *contraption.Context, h int) *contraption.Font {
+	if f, ok := rer.fonts[h]; ok {
+		return f
+	}
+	if h < 0 || h >= len(c.Fonts) || c.Fonts[h].Deleted {
+		return nil
+	}
+	f, err := contraption.NewFont(nil, c.Fonts[h].Data, ``)
+	if err != nil {
+		f = nil
+	}
+	rer.fonts[h] = f
+	return f
+}
+
+func (rer *Renderer) text(c *contraption.Context, l *contraption.RenderOp) {
+	s := rer.ip.State()
+	f := rer.font(c, s.Font)
+	if f == nil {
+		return
+	}
+	// Font size is a cap height in Contraption.
+	em := float32(f.Captoem(float64(s.FontSize)))
+	x, y := float32(l.Args[1]), float32(l.Args[2])
+	col := s.Fill.Inner
+	fill := fmt.Sprintf(`fill="%s" fill-opacity="%s"`, rgb(col), num(col.A*s.Alpha))
+	clip := rer.clip()
+
+	if !rer.Outline {
+		used := false
+		for _, h := range rer.usedfonts {
+			used = used || h == s.Font
+		}
+		if !used {
+			rer.usedfonts = append(rer.usedfonts, s.Font)
+		}
+		// Clip path is in the coordinates of the element, so it is not applied to the transformed text directly.
+		if clip != `` {
+			fmt.Fprintf(&rer.buf, `<g%s>`, clip)
+		}
+		fmt.Fprintf(&rer.buf, `<text x="%s" y="%s" font-family="%s" font-size="%s" transform="%s" %s xml:space="preserve">`,
+			num(x), num(y), fontfamily(s.Font), num(em), matrix(s.Xform), fill)
+		xml.EscapeText(&rer.buf, []byte(string(l.Runes)))
+		rer.buf.WriteString("</text>")
+		if clip != `` {
+			rer.buf.WriteString("</g>")
+		}
+		rer.buf.WriteString("\n")
+		return
+	}
+
+	var path []interp.Cmd
+	t := s.Xform
+	pt := func(p interp.Point) interp.Point {
+		p.X, p.Y = t.TransformPoint(x+p.X*em, y+p.Y*em)
+		return p
+	}
+	for _, r := range l.Runes {
+		var last interp.Point
+		for _, seg := range f.Segments(r) {
+			a := [3]interp.Point{}
+			for i, p := range seg.Args {
+				a[i] = interp.Point{X: float32(p.X), Y: float32(p.Y)}
+			}
+			switch seg.Op {
+			case 'M':
+				if len(path) > 0 {
+					path = append(path, interp.Cmd{Op: 'Z'})
+				}
+				path = append(path, interp.Cmd{Op: 'M', Pts: [3]interp.Point{pt(a[0])}})
+				last = a[0]
+			case 'L':
+				path = append(path, interp.Cmd{Op: 'L', Pts: [3]interp.Point{pt(a[0])}})
+				last = a[0]
+			case 'Q':
+				c1 := interp.Point{X: last.X + 2.0/3.0*(a[0].X-last.X), Y: last.Y + 2.0/3.0*(a[0].Y-last.Y)}
+				c2 := interp.Point{X: a[1].X + 2.0/3.0*(a[0].X-a[1].X), Y: a[1].Y + 2.0/3.0*(a[0].Y-a[1].Y)}
+				path = append(path, interp.Cmd{Op: 'C', Pts: [3]interp.Point{pt(c1), pt(c2), pt(a[1])}})
+				last = a[1]
+			case 'C':
+				path = append(path, interp.Cmd{Op: 'C', Pts: [3]interp.Point{pt(a[0]), pt(a[1]), pt(a[2])}})
+				last = a[2]
+			}
+		}
+		x += float32(f.Advance(r)) * em
+	}
+	if len(path) == 0 {
+		return
+	}
+	path = append(path, interp.Cmd{Op: 'Z'})
+	fmt.Fprintf(&rer.buf, `<path d="%s" %s%s/>`+"\n", pathdata(path), fill, clip)
+}
@@ -0,0 +1,139 @@
+package svg_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/neputevshina/contraption"
+	"github.com/neputevshina/contraption/backends/headless"
+	"github.com/neputevshina/contraption/backends/software"
+	"github.com/neputevshina/contraption/backends/svg"
+	"github.com/neputevshina/contraption/nanovgo"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// element is a start element of the document with the text right inside of it.
+type element struct {
+	xml.StartElement
+	text string
+}
+
+func (e element) attr(name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ``
+}
+
+// parse returns the elements of the document by their names.
+func parse(t *testing.T, doc []byte) map[string][]element {
+	t.Helper()
+	els := map[string][]element{}
+	var open []string
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("document is not well-formed: %v", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			els[tok.Name.Local] = append(els[tok.Name.Local], element{StartElement: tok.Copy()})
+			open = append(open, tok.Name.Local)
+		case xml.CharData:
+			if len(open) > 0 {
+				l := els[open[len(open)-1]]
+				l[len(l)-1].text += string(tok)
+			}
+		case xml.EndElement:
+			open = open[:len(open)-1]
+		}
+	}
+	return els
+}
+
+func TestExport(t *testing.T) {
+	wer := headless.New(100, 100, 1)
+	wo := contraption.New(wer, software.New(), contraption.Config{})
+	text := wo.NewText(goregular.TTF)
+	red := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(red, red.Rect, image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	h := wo.Vgo.CreateImageFromGoImage(0, red)
+	grad := nanovgo.LinearGradient(0, 0, 0, 20, nanovgo.RGB(255, 0, 0), nanovgo.RGB(0, 0, 255))
+	black := nanovgo.LinearGradient(0, 0, 1, 1, nanovgo.RGB(0, 0, 0), nanovgo.RGB(0, 0, 0))
+	for i := 0; i < 2 && wo.Next(); i++ {
+		wo.Root(wo.Compound(wo.Vfollow(), wo.Crop(), wo.Limit(60, 60),
+			wo.Rectangle(50, 20).Fill(grad),
+			wo.Rectangle(10, 10).Fill(nanovgo.ImagePattern(0, 20, 10, 10, 0, h, 1)),
+			text(10, []rune("Hi")).Fill(black)))
+		wo.Develop()
+	}
+	rer := svg.New(nil)
+	wo.Export(rer)
+	els := parse(t, rer.Document)
+
+	if s := els["svg"]; len(s) != 1 || s[0].attr("width") != "100" || s[0].attr("height") != "100" {
+		t.Errorf("root is %v, want a single svg of 100×100", s)
+	}
+	var stops []string
+	for _, s := range els["stop"] {
+		stops = append(stops, s.attr("stop-color"))
+	}
+	if len(els["linearGradient"]) != 1 || strings.Join(stops, " ") != "#ff0000 #0000ff" {
+		t.Errorf("%d linear gradients with stops %v, want one from #ff0000 to #0000ff", len(els["linearGradient"]), stops)
+	}
+
+	// Everything is cropped by the same clip path.
+	if len(els["clipPath"]) != 1 {
+		t.Fatalf("%d clip paths, want 1", len(els["clipPath"]))
+	}
+	clip := "url(#" + els["clipPath"][0].attr("id") + ")"
+	for _, p := range els["path"] {
+		if p.attr("clip-path") != clip {
+			t.Errorf("path %s is clipped by %q, want %q", p.attr("d"), p.attr("clip-path"), clip)
+		}
+	}
+	// Text is transformed, so it is clipped by a group around it.
+	if g := els["g"]; len(g) != 1 || g[0].attr("clip-path") != clip {
+		t.Errorf("text is not clipped by %q", clip)
+	}
+
+	if len(els["image"]) != 1 {
+		t.Fatalf("%d images, want 1", len(els["image"]))
+	}
+	href, ok := strings.CutPrefix(els["image"][0].attr("href"), "data:image/png;base64,")
+	if !ok {
+		t.Fatalf("image is not an embedded PNG")
+	}
+	b, err := base64.StdEncoding.DecodeString(href)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.RGBAModel.Convert(m.At(5, 5)); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("image is %v, want red", c)
+	}
+
+	if tx := els["text"]; len(tx) != 1 || tx[0].text != "Hi" {
+		t.Errorf("text is %v, want a single Hi", tx)
+	}
+	if s := els["style"]; len(s) != 1 || !strings.Contains(s[0].text, "@font-face") {
+		t.Errorf("font is not embedded")
+	}
+}
@@ -38,36 +38,36 @@
 //	- Anchors
 //		- For Curve aligner and other CAD-like features
 //		- Are just numbers
-//	- Textbox behavior
-//		- https://rxi.github.io/textbox_behaviour.html
+//	± Textbox behavior
+//		+ https://rxi.github.io/textbox_behaviour.html
 //		- Implemented over Sequence
-//			- TextSequence interface { Backspace(i, j), Delete(i, j), Insert(i, j), Copy(i, j) etc }
+//			+ TextSequence interface { Backspace(i, j), Delete(i, j), Insert(i, j), Copy(i, j) etc }
 //			- Operation-level control for journaled text (OT, CRDTs)
 //		- Sequence cropping will provide efficiency.
 //		- Editable() modifier
 //			- Aligner and Align will change the behavior.
 //		- Elements that are not in TextSequences can not be edited
 //			- But can be copied.
-//	- Activator stack
-//	- Word layout
+//	+ Activator stack
+//	+ Word layout
 //		- Together with stretch creates a flexbox-like system
 //		- Together with laziness creates a universal layout framework, capable of word processing
 //		- Will be used for text.
 //		- func Hwords(perline func() Sorm) Sorm
 //		- func Vwords(perline func() Sorm) Sorm
 //		- Secondary axis limits influenced by perpendicular Void
-//		- wo.Text(io.RuneScanner) []Sorm
+//		+ wo.Text(io.RuneScanner) []Sorm
 //			- Returns Knuth-Plass-ready stream of boxes, Glues and Penalties.
 //			? How to insert anything in between symbols?
 //				? RuneScanner splitter?
 //		- wo.Cap(float64) (can't be negative)
 //		- wo.Lsp(float64)
-//		- Knuth-Plass
+//		+ Knuth-Plass
 //			? Interpret negative sizes as glue.
-// 			- func Hknuth(perline func() Sorm) Sorm
-// 			- func Vknuth(perline func() Sorm) Sorm
-// 			- func Glue(width, minus, plus float64) Sorm // Analogous to wo.Void() but undirectional.
-// 			- func Penalty(replacewith func() Sorm, penalty float64) Sorm
+// 			+ func Hknuth(perline func() Sorm) Sorm
+// 			+ func Vknuth(perline func() Sorm) Sorm
+// 			+ func Glue(width, minus, plus float64) Sorm // Analogous to wo.Void() but undirectional.
+// 			+ func Penalty(replacewith Sorm, penalty float64) Sorm
 //				- Alt: Penalty as a builder on a target shape
 //			- Void(0, y) is already a “strut”
 //	- Investigate better ways to specify size values
@@ -97,7 +97,7 @@
 //		- Add another two values to every Sorm constructor
 //			- Requires major refactoring
 //			- Looks overcomplicated
-//	- Sequence buffer size hints
+//	+ Sequence buffer size hints
 //		- 16 elements on complex and large items
 //		- 128 on moderately complex and medium-sized items (messages in chat)
 //		- 1024 on small and simple elements (rows of data, histograms)
@@ -107,17 +107,18 @@
 //		- Probably when implementing this will be the best time to go for
 //			geom.Rect for storing xywh
 //	? LimitOverride
-//	- Grid aligner
+//	+ Grid aligner
 //		- wo.Hgrid(cols int) + wo.Halign() — secondary alignment
 //		- wo.Vgrid(rows int) + wo.Valign()
 //		- Primary alignment won't work — makes no sense
-//		- Negative sizes in primary axis won't work.
+//		- Negative sizes in primary axis are distributed only in the first line.
 //		- No instructions for items like in CSS grid.
 //		- Negative sizes in secondary axis are distributed.
-//	- BufferSequence
-//		- MemoBufferSequence
-//	- RuneScannerSequence
-//		- Needs recording of the whole given RuneScanner, at least with current Sequence implementation.
+//	+ BufferSequence
+//		+ MemoBufferSequence
+//	+ RuneScannerSequence
+//		± Needs recording of the whole given RuneScanner, unless it is an io.Seeker.
+//		+ ReadSeekerSequence for files.
 //	± Imaginary sizes.
 //		- -1 + 20i — negative stretch, add 20 scaled by local transform pixels to size on layout step
 //		- -1 - 20i  <  -1  <  -1 + 20i
@@ -135,22 +136,26 @@
 //	- File drag event: Drag(*.txt)
 //		- A companion for Drop — matches when the file is dragged above the area.
 //		- Needs changes in GLFW or changing input library. SDL supports this.
-//	- Interactive views for very large 1d and 2d data: waveforms, giant Minecraft maps, y-log STFT frames, etc.
-//		- Easy insertion of Sorms between the data. See https://www.youtube.com/watch?v=Cz0OvnR_aoY.
+//	+ Interactive views for very large 1d and 2d data: waveforms, giant Minecraft maps, y-log STFT frames, etc.
+//		+ Easy insertion of Sorms between the data. See https://www.youtube.com/watch?v=Cz0OvnR_aoY.
 //			- Probably very easy to implement by simply slicing the data.
 //		- Why? Try to display STFT of a music file using Matplotlib, then rescale the window. Enjoy the delay.
 //	+ Sequence must be a special shape that pastes Sorms inside a compound, not being compound itself
 //		- So wo.Text(io.RuneReader) could be Sequence
 //		+ Not clear how to reuse memory of pools in this case
-//	- Matching past in regexps and coords change [MAJOR TOPIC]
-//		- Easily solved with hitmaps — just draw a hitmap with all component's transformations
+//	+ Matching past in regexps and coords change [MAJOR TOPIC]
+//		+ Easily solved with hitmaps — just draw a hitmap with all component's transformations
 //		- Could use per-event UV deltas, but 64x viewport memory overhead is too much
 //		- Use VDOM — retain, reconcile and feedback
 //		- Save matrix for every shape that looks behind, 64×8×16×[shape count] bytes of overhead
 //			- Simpler version: for every shape that has Cond/CondPaint, which is larger but still less than 10% of a tree
-//	- Laziness and scrolling [MAJOR TOPIC]
+//	± Laziness and scrolling [MAJOR TOPIC]
 //		+ wo.Sequence(seq Sequence) — a window to infinity!
 //			+ Every returned Sorm is included to the parent
+//		+ Hscroll and Vscroll request only elements that are seen
+//			- Only follow aligners can be scrolled
+//		+ Scrollbars
+//		+ Chats, aligned to the end with Valign(1)
 //	- Non-trivial layout
 //		- Timeline
 //			- See how clip names behave in almost every DAW.
@@ -159,7 +164,7 @@
 //			- func Huniform(...Sorm) Sorm
 //			- func Vuniform(...Sorm) Sorm
 //			- Can use new negative value behavior? Just make needed widths/heights equal negative values.
-//			- Integer key to determine which sizes must be equal:
+//			+ Integer key to determine which sizes must be equal:
 //				- func Eqkey() Eqkey
 //				- func Hequal(Eqkey) Sorm
 //				- func Vequal(Eqkey) Sorm
@@ -232,6 +237,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"time"
 	"unsafe"
 
 	"github.com/neputevshina/contraption/nanovgo"
@@ -263,6 +269,13 @@ const (
 	flagIteratedScissor
 	flagNoround
 	flagRound
+	flagInserted   // Inserted by Between
+	flagDecoration // Line decoration of Hwords or Vwords
+	flagDecorated  // Compound with line decorations, see decorate
+	flagReplacement
+	flagHidden
+	flagFocusable
+	flagHscroll // Scrolled by Hscroll, if the aligner is not Hfollow or Vfollow
 )
 
 //go:generate stringer -type=tagkind -trimprefix=tag
@@ -288,6 +301,10 @@ const (
 	tagBottomUpText
 	tagSequence
 	tagIllustration
+	tagGlue
+	tagPenalty
+	tagParagraph
+	tagTextbox
 )
 const (
 	_ tagkind = -iota
@@ -304,8 +321,7 @@ const (
 	tagScroll
 	tagSource
 	tagSink
-	tagHscroll
-	tagVscroll
+	tagFocusable
 )
 const (
 	_ tagkind = -100 - iota
@@ -319,11 +335,27 @@ const (
 	tagHfollow
 	tagNoround
 	tagRound
+	tagHgrid
+	tagVgrid
+	tagHequal
+	tagVequal
+	tagHwords
+	tagVwords
+	tagHknuth
+	tagVknuth
+	tagHscroll
+	tagVscroll
 )
 const (
 	alignerNone alignerkind = iota
 	alignerVfollow
 	alignerHfollow
+	alignerHgrid
+	alignerVgrid
+	alignerHwords
+	alignerVwords
+	alignerHknuth
+	alignerVknuth
 )
 
 // NOTE This might actually be a single table, if needed.
@@ -341,6 +373,10 @@ func init() {
 	shapeActions[tagRect] = rectrun
 	shapeActions[tagRoundrect] = roundrectrun
 	shapeActions[tagVoid] = voidrun
+	shapeActions[tagGlue] = gluerun
+	shapeActions[tagPenalty] = penaltyrun
+	shapeActions[tagParagraph] = paragraphrun
+	shapeActions[tagTextbox] = textboxrun
 	shapeActions[tagEquation] = equationrun
 	shapeActions[tagCanvas] = canvasrun
 	shapeActions[tagVectorText] = vectortextrun
@@ -362,6 +398,7 @@ func init() {
 	modActions[-tagBetween] = betweenrun
 	modActions[-tagSource] = sourcerun
 	modActions[-tagSink] = sinkrun
+	modActions[-tagFocusable] = focusablerun
 
 	preActions[-100-tagPosttransform] = posttransformrun
 	preActions[-100-tagTransform] = transformrun
@@ -373,10 +410,26 @@ func init() {
 	preActions[-100-tagLimit] = limitrun
 	preActions[-100-tagNoround] = noroundrun
 	preActions[-100-tagRound] = roundrun
+	preActions[-100-tagHgrid] = hgridrun
+	preActions[-100-tagVgrid] = vgridrun
+	preActions[-100-tagHequal] = hequalrun
+	preActions[-100-tagVequal] = vequalrun
+	preActions[-100-tagHwords] = hwordsrun
+	preActions[-100-tagVwords] = vwordsrun
+	preActions[-100-tagHknuth] = hknuthrun
+	preActions[-100-tagVknuth] = vknuthrun
+	preActions[-100-tagHscroll] = hscrollrun
+	preActions[-100-tagVscroll] = vscrollrun
 
 	alignerActions[alignerNone] = noaligner
 	alignerActions[alignerVfollow] = vfollowaligner
 	alignerActions[alignerHfollow] = hfollowaligner
+	alignerActions[alignerHgrid] = hgridaligner
+	alignerActions[alignerVgrid] = vgridaligner
+	alignerActions[alignerHwords] = hwordsaligner
+	alignerActions[alignerVwords] = vwordsaligner
+	alignerActions[alignerHknuth] = hknuthaligner
+	alignerActions[alignerVknuth] = vknuthaligner
 }
 
 type Eqn func(pt geom.Point) (dist float64)
@@ -411,8 +464,9 @@ type World struct {
 	*Events
 	gen int
 
-	rer Renderer
-	wer Windower
+	rer     Renderer
+	wer     Windower
+	exports []Renderer
 
 	tmp []*Sorm
 
@@ -442,16 +496,27 @@ type World struct {
 	sinks     []func(any)
 	drags     map[reflect.Type]func(interval [2]geom.Point, drag any) *Sorm
 
+	actives    []Activator
+	activated  time.Time // Time of the last dispatched event
+	focus      any
+	focusables []any // Keys of focusable compounds of the previous frame in layout order
+
 	showOutlines bool
 	f1           bool
 
 	alloc func(n int) (left, right int)
 
 	images map[io.Reader]imagestruct
+	tiles  map[dvtile]dvtexture // Textures of Tilemaps
 
 	hasher  hash.Hash // Current tree hash
 	oldhash [16]byte  // Previous tree hash
 
+	eqkeys  Eqkey
+	eqpass  int // 0 — nothing to equalize, 1 — measuring layout, 2 — final layout
+	eqsizes map[Eqkey]point
+	eqsnap  []Sorm
+	eqidx   map[*Index]Index // Indices before the measuring layout
 }
 
 func (wo *World) Renderer() Renderer {
@@ -462,6 +527,17 @@ func (wo *World) Windower() Windower {
 	return wo.wer
 }
 
+// Export passes the frame to the renderer once, in addition to the main renderer.
+// If called after Develop, the last frame is exported immediately, otherwise
+// the current frame is exported after Develop completes it.
+func (wo *World) Export(rer Renderer) {
+	if wo.Vgo.state == 0 {
+		rer.Run(wo.Vgo)
+		return
+	}
+	wo.exports = append(wo.exports, rer)
+}
+
 type imagestruct struct {
 	gen     int
 	texid   int
@@ -483,6 +559,7 @@ func (wo *World) ResetDrags() {
 
 type Sorm struct {
 	z, z2, i, pi int
+	zj, zr       int // Index and z2 of the Sequence element a virtual Sorm belongs to
 	tag          tagkind
 	flags        flagval
 
@@ -515,12 +592,19 @@ type Sorm struct {
 	fontid  int
 	vecfont *Font
 
+	eq [2]Eqkey // Keys of Hequal and Vequal
+
 	// Some objects use key field for own purposes:
 	// 	- Equation stores an Equation object
 	// 	- Text stores a io.RuneReader
 	// 	- Compound stores an Identity, which also works
 	//	  out as Source's dropable object
 	// 	- Between stores func() Sorm
+	// 	- Glue stores its shrink and stretch
+	// 	- Penalty stores its replacement
+	// 	- Paragraph stores its text and layout
+	// 	- Textbox stores its state and text
+	// 	- Hwords, Vwords, Hknuth and Vknuth store the func making line decorations
 	key any
 
 	condfill       func(rect geom.Rectangle) nanovgo.Paint
@@ -535,9 +619,34 @@ type Sorm struct {
 	callerfile string
 }
 
+// Index is a position of a scrolled compound.
+//
+// I is the kid seen first, where every element of a Sequence counts as a kid,
+// and O is how far the compound is scrolled past its start.
+// In chats, which are aligned to the end, I is the kid seen last counted from the end,
+// and O is how far the compound is scrolled back from its end.
+// Index is normalized by the layout, so O can be set to any value to scroll by it.
 type Index struct {
 	I int
 	O float64
+
+	chat bool // Counted from the end
+	end  bool // Scrolled to the end, see (*Index).End
+
+	// Smooth scrolling, see (*Index).advance.
+	rest     float64   // Distance left to scroll
+	v        float64   // Momentum in units per second
+	burst    int       // Count of events in the current burst
+	last     time.Time // Time of the last event
+	t        time.Time // Time of the last frame
+	dragging bool
+
+	// Scrollbars, see (*World).Vscrollbar.
+	at, seen float64        // Position and count of seen kids, both fractional
+	avg      float64        // Running average size of a kid
+	view     float64        // Length of the viewport
+	rect     geom.Rectangle // Of the compound in the previous frame
+	grab     float64        // Position of the thumb when it was grabbed
 }
 
 func (s Sorm) auxkids(wo *World) []*Sorm {
@@ -847,6 +956,13 @@ func (wo *World) compound2(s *Sorm, realroot bool, args ...*Sorm) *Sorm {
 		s.l.Y = wo.Hwin
 	}
 
+	// Replacements of Penalties are kids only in Hknuth and Vknuth, other aligners don't break at Penalties.
+	knuth := false
+	for _, a := range args {
+		if a != nil && (a.tag == tagHknuth || a.tag == tagVknuth) {
+			knuth = true
+		}
+	}
 	for _, a := range args {
 		if a == nil {
 			continue
@@ -854,6 +970,11 @@ func (wo *World) compound2(s *Sorm, realroot bool, args ...*Sorm) *Sorm {
 		if a.tag == tagBetween {
 			void = a.key.(func() *Sorm)
 		}
+		if a.tag == tagPenalty && a.key != nil && knuth {
+			// Replacement goes right after its Penalty and doesn't get a Between.
+			kidc++
+			btwc++
+		}
 		switch {
 		case a.tag >= 0:
 			kidc++
@@ -875,7 +996,9 @@ func (wo *World) compound2(s *Sorm, realroot bool, args ...*Sorm) *Sorm {
 		// Place new voids into temporary storage so their allocations won't
 		// break breadth-first order of the pool.
 		for i := 0; i < voidc; i++ {
-			wo.tmp = append(wo.tmp, void())
+			v := void()
+			v.flags |= flagInserted
+			wo.tmp = append(wo.tmp, v)
 		}
 	}
 
@@ -891,6 +1014,14 @@ func (wo *World) compound2(s *Sorm, realroot bool, args ...*Sorm) *Sorm {
 		if a.tag >= 0 {
 			s.kids2(wo)[i] = a
 			i++
+			if a.tag == tagPenalty && a.key != nil {
+				r := a.key.(*Sorm)
+				r.flags |= flagReplacement | flagHidden
+				if knuth {
+					s.kids2(wo)[i] = r
+					i++
+				}
+			}
 			if void != nil && voidc > 0 && !(a.flags&flagBetweener > 0) {
 				s.kids2(wo)[i] = wo.tmp[tmpn]
 				voidc--
@@ -964,6 +1095,16 @@ func (s *Sorm) kidsiter(wo *World, a kiargs, f func(k *Sorm)) {
 	if s.tag == tagSequence {
 		return
 	}
+	if s.flags&flagDecorated > 0 {
+		// Line decorations go before kids, like they are drawn.
+		for _, m := range s.pres(wo) {
+			if m.decorated() && m.flags&flagSequenceSaved > 0 {
+				for _, d := range wo.pool[m.presl:m.presr] {
+					f(d)
+				}
+			}
+		}
+	}
 
 	kids := wo.pool[s.kidsl:s.kidsr]
 out:
@@ -973,38 +1114,11 @@ out:
 		q, ok := k.key.(Sequence)
 		if ok {
 			if k.flags&flagSequenceSaved == 0 {
-				reall := len(wo.auxpool)
-
-				// Treat the aux pool as the main pool and the sequence as the root compound.
-				pop := wo.beginvirtual()
-				wo.prefix = k.z
-				wo.bufferstash = wo.bufferstash[:0]
 				var buf [32]*Sorm
-			out2:
-				for i := 0; i < q.Length(wo); i += len(buf) {
-					// println(i, len(buf), i+len(buf), q.Length(wo))
-					n := q.Get(wo, i, buf[:])
-					for j := 0; j < min(n, len(buf)); j++ {
-						f(buf[j])                                // (1)
-						if buf[j].flags&flagBreakIteration > 0 { // (2)
-							break out2
-						}
-					}
-					wo.bufferstash = append(wo.bufferstash, buf[:min(n, len(buf))]...)
-				}
-				wo.prefix = 0
-				wo.endvirtual(pop)
-				// Copy elements materialized from the sequence to the auxpool,
-				// treat them like arguments of (*World).Compound
-				l, r := wo.allocaux(len(wo.bufferstash))
-				copy(wo.auxpool[l:r], wo.bufferstash)
-
-				k.kidsl = reall
-				k.kidsr = r
-				// Save immediate kids.
-				k.presl = l
-				k.presr = r
-				k.flags |= flagSequenceSaved
+				wo.materialize(k, q, 0, buf[:], func(k *Sorm) bool {
+					f(k)                                   // (1)
+					return k.flags&flagBreakIteration == 0 // (2)
+				})
 			} else {
 				aux := wo.auxpool[k.presl:k.presr]
 				for i := range aux {
@@ -1026,6 +1140,62 @@ out:
 	}
 }
 
+// materialize gets elements of the Sequence k from the j-th by chunks of len(buf) and saves them
+// to the aux pool. f is called for every element as soon as it is made, materialization
+// stops after the element for which f returned false.
+func (wo *World) materialize(k *Sorm, q Sequence, j int, buf []*Sorm, f func(k *Sorm) bool) {
+	reall := len(wo.auxpool)
+
+	// Treat the aux pool as the main pool and the sequence as the root compound.
+	pop := wo.beginvirtual()
+	wo.prefix = k.z
+	wo.bufferstash = wo.bufferstash[:0]
+out:
+	for i := j; i < q.Length(wo); i += len(buf) {
+		n := q.Get(wo, i, buf)
+		for _, e := range buf[:max(0, min(n, len(buf)))] {
+			wo.bufferstash = append(wo.bufferstash, e)
+			if !f(e) {
+				break out
+			}
+		}
+	}
+	wo.prefix = 0
+	wo.endvirtual(pop)
+	// Copy elements materialized from the sequence to the auxpool,
+	// treat them like arguments of (*World).Compound
+	l, r := wo.allocaux(len(wo.bufferstash))
+	copy(wo.auxpool[l:r], wo.bufferstash)
+	wo.elements(j, wo.auxpool[reall:l], wo.auxpool[l:r])
+
+	k.kidsl = reall
+	k.kidsr = r
+	// Save immediate kids.
+	k.presl = l
+	k.presr = r
+	k.flags |= flagSequenceSaved
+}
+
+// elements marks materialized Sorms with the Sequence elements they belong to, from the j-th.
+// Sorms of an element are made before it, after the previous element, so they are found by z2.
+func (wo *World) elements(j int, sorms, elems []*Sorm) {
+	byz2 := slices.Clone(elems)
+	for i, e := range elems {
+		e.zj, e.zr = j+i, e.z2
+	}
+	slices.SortFunc(byz2, func(a, b *Sorm) int {
+		return a.z2 - b.z2
+	})
+	for _, s := range sorms {
+		i, _ := slices.BinarySearchFunc(byz2, s.z2, func(e *Sorm, z2 int) int {
+			return e.z2 - z2
+		})
+		if i < len(byz2) {
+			s.zj, s.zr = byz2[i].zj, byz2[i].zr
+		}
+	}
+}
+
 func (wo *World) topbreadthiter(pool []*Sorm, f func(s, _ *Sorm)) {
 	wo.breadthiter(pool, f, false)
 }
@@ -1044,24 +1214,29 @@ func (wo *World) breadthiter(pool []*Sorm, f func(s, _ *Sorm), bottomup bool) {
 		d = +1
 	}
 
-	for i := z(pool); p(i, pool); i += d {
-		s := pool[i]
-		if s.tag == tagSequence {
-			v := wo.beginvirtual()
-			pool := wo.auxpool[s.kidsl:s.kidsr]
-			for j := z(pool); p(j, pool); j += d {
-				s := pool[j]
+	var iter func(pool []*Sorm)
+	iter = func(pool []*Sorm) {
+		for i := z(pool); p(i, pool); i += d {
+			s := pool[i]
+			switch {
+			case s.tag == tagSequence:
+				v := wo.beginvirtual()
+				iter(wo.pool[s.kidsl:s.kidsr])
+				wo.endvirtual(v)
+			case s.decorated() && s.flags&flagSequenceSaved > 0:
+				// Line decorations are made after the pool by the aligner, see decorate.
+				if wo.allowed(s) {
+					f(s, s)
+				}
+				iter(wo.pool[s.kidsl:s.kidsr])
+			default:
 				if wo.allowed(s) {
 					f(s, s)
 				}
-			}
-			wo.endvirtual(v)
-		} else {
-			if wo.allowed(s) {
-				f(s, s)
 			}
 		}
 	}
+	iter(pool)
 }
 
 // If a Compound has no aligner set (“stack” layout)
@@ -1149,6 +1324,8 @@ func trypos(p, a geom.Point) geom.Point {
 func followdivider(wo *World, c *Sorm, h bool) {
 	beginaxis, endaxis := axis(h)
 	beginaxis(c)
+	// A subtree can be divided again if it is laid out again.
+	c.knowns, c.props = point{}, point{}
 	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
 		beginaxis(k)
 		if k.tag == 0 {
@@ -1175,6 +1352,10 @@ func followdivider(wo *World, c *Sorm, h bool) {
 }
 
 func followaligner(wo *World, c *Sorm, h bool) {
+	if c.idx != nil {
+		scrollaligner(wo, c, h)
+		return
+	}
 	c.Size.X, c.Size.Y = 0, 0
 	beginaxis, endaxis := axis(h)
 	// Calculate unknowns and apply kids which sizes were unknown,
@@ -1231,6 +1412,371 @@ func followaligner(wo *World, c *Sorm, h bool) {
 	endaxis(c)
 }
 
+func vgridaligner(wo *World, c *Sorm) {
+	gridaligner(wo, c, false)
+}
+
+func hgridaligner(wo *World, c *Sorm) {
+	gridaligner(wo, c, true)
+}
+
+// selfaligned reports if the aligner of a compound handles Halign and Valign by itself.
+func (c *Sorm) selfaligned() bool {
+	switch c.aligner {
+	case alignerHgrid, alignerVgrid, alignerHwords, alignerVwords, alignerHknuth, alignerVknuth:
+		return true
+	}
+	return false
+}
+
+// selfalignment returns the alignment of kids inside grid cells or lines.
+func selfalignment(wo *World, c *Sorm) (ax, ay float64) {
+	for _, m := range c.mods(wo) {
+		switch m.tag {
+		case tagHalign:
+			ax = m.Size.X
+		case tagValign:
+			ay = m.Size.X
+		}
+	}
+	return
+}
+
+// stretchof returns stretch coefficients of a kid.
+func stretchof(k *Sorm) point {
+	if k.tag == 0 {
+		return k.eprops
+	}
+	return point{X: -min(0, k.Size.X), Y: -min(0, k.Size.Y)}
+}
+
+// gridaligner lays kids out in lines of c.r cells.
+// Like in followaligner, Y is the main axis, lines are stacked along X.
+// Sizes of tracks along the main axis are set by the first line,
+// stretchy tracks and lines share the rest of the limit.
+func gridaligner(wo *World, c *Sorm, h bool) {
+	n := max(1, int(c.r))
+	ax, ay := selfalignment(wo, c)
+	if h {
+		ax, ay = ay, ax
+	}
+	c.Size.X, c.Size.Y = 0, 0
+	beginaxis, endaxis := axis(h)
+	beginaxis(c)
+
+	// Measure tracks and lines.
+	// Stretchy compounds were already applied with the whole limit, so their sizes are not used.
+	var tracks, tprops, lines, lprops []float64
+	i := 0
+	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
+		beginaxis(k)
+		j := i / n
+		e := stretchof(k)
+		if j == 0 {
+			if e.Y > 0 {
+				tracks = append(tracks, 0)
+			} else {
+				tracks = append(tracks, k.Size.Y)
+			}
+			tprops = append(tprops, e.Y)
+		}
+		if j == len(lines) {
+			lines = append(lines, 0)
+			lprops = append(lprops, 0)
+		}
+		if e.X > 0 {
+			lprops[j] = max(lprops[j], e.X)
+		} else {
+			lines[j] = max(lines[j], k.Size.X)
+		}
+		endaxis(k)
+		i++
+	})
+
+	// Distribute the rest of the limit between stretchy tracks and lines.
+	distribute(tracks, tprops, c.l.Y, c.decimated())
+	distribute(lines, lprops, c.l.X, c.decimated())
+
+	tpos := make([]float64, len(tracks)+1)
+	for t := range tracks {
+		tpos[t+1] = tpos[t] + tracks[t]
+	}
+	lpos := make([]float64, len(lines)+1)
+	for j := range lines {
+		lpos[j+1] = lpos[j] + lines[j]
+	}
+
+	i = 0
+	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
+		t, j := i%n, i/n
+		i++
+		stretch := false
+
+		beginaxis(k)
+		e := stretchof(k)
+		if e.Y > 0 {
+			k.Size.Y = tracks[t]
+			k.l.Y = k.Size.Y
+			stretch = true
+		}
+		if e.X > 0 {
+			k.Size.X = lines[j]
+			k.l.X = k.Size.X
+			stretch = true
+		}
+		endaxis(k)
+
+		if stretch {
+			wo.apply(c, k)
+		}
+
+		beginaxis(k)
+		// Stop laying out kids if we're clipped out of limit, unless they are scrolled into it.
+		if lpos[j] > c.l.X && c.idx == nil {
+			k.flags |= flagBreakIteration
+		}
+		k.p.X = lpos[j] + max(0, lines[j]-k.Size.X)*ax
+		k.p.Y = tpos[t] + max(0, tracks[t]-k.Size.Y)*ay
+		endaxis(k)
+	})
+	c.Size.X = lpos[len(lines)]
+	c.Size.Y = tpos[len(tracks)]
+	if c.decimated() {
+		c.Size.X = math.Round(c.Size.X)
+		c.Size.Y = math.Round(c.Size.Y)
+	}
+	endaxis(c)
+}
+
+// distribute gives the rest of the limit l to sizes with positive props.
+// Nothing is distributed if the limit is infinite.
+func distribute(sizes, props []float64, l float64, round bool) {
+	known, sum := 0.0, 0.0
+	for j := range sizes {
+		if props[j] > 0 {
+			sum += props[j]
+		} else {
+			known += sizes[j]
+		}
+	}
+	if sum == 0 || math.IsInf(l, 0) {
+		return
+	}
+	for j := range sizes {
+		if props[j] > 0 {
+			sizes[j] = max(sizes[j], (l-known)/sum*props[j])
+			if round {
+				sizes[j] = math.Round(sizes[j])
+			}
+		}
+	}
+}
+
+func vwordsaligner(wo *World, c *Sorm) {
+	wordsaligner(wo, c, false)
+}
+
+func hwordsaligner(wo *World, c *Sorm) {
+	wordsaligner(wo, c, true)
+}
+
+// wordsaligner breaks kids into lines that fit into the limit.
+// Like in followaligner, Y is the main axis, lines are stacked along X.
+//
+// Sorms inserted by Between are collapsed at line breaks.
+// Kids with negative sizes in the main axis share the rest of their line,
+// in the secondary axis they take the thickness of the line.
+func wordsaligner(wo *World, c *Sorm, h bool) {
+	type line struct {
+		l, r   int // Kids of a line, Sorms inserted after r are collapsed.
+		length float64
+		free   float64 // Space shared by stretched kids
+		thick  float64
+		props  float64
+		pos    point
+	}
+	ax, ay := selfalignment(wo, c)
+	if h {
+		ax, ay = ay, ax
+	}
+	c.Size.X, c.Size.Y = 0, 0
+	beginaxis, endaxis := axis(h)
+	beginaxis(c)
+
+	// Break kids into lines.
+	var lines []line
+	cur := line{}
+	pending := 0.0
+	i := 0
+	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
+		if k.flags&flagDecoration > 0 {
+			return
+		}
+		beginaxis(k)
+		e := stretchof(k)
+		y := 0.0
+		if e.Y == 0 {
+			y = k.Size.Y
+		}
+		if k.flags&flagInserted > 0 {
+			pending += y
+		} else {
+			if cur.r > cur.l && cur.length+pending+y > c.l.Y {
+				lines = append(lines, cur)
+				cur = line{l: i}
+			} else {
+				cur.length += pending
+			}
+			pending = 0
+			cur.length += y
+			cur.props += e.Y
+			if e.X == 0 {
+				cur.thick = max(cur.thick, k.Size.X)
+			}
+			cur.r = i + 1
+		}
+		endaxis(k)
+		i++
+	})
+	if cur.r > cur.l {
+		lines = append(lines, cur)
+	}
+
+	// Stretch lines and align them.
+	for j := range lines {
+		ln := &lines[j]
+		if ln.props > 0 {
+			ln.free = max(0, c.l.Y-ln.length)
+			ln.length += ln.free
+		}
+		c.Size.Y = max(c.Size.Y, ln.length)
+	}
+	x := 0.0
+	for j := range lines {
+		ln := &lines[j]
+		ln.pos = point{X: x, Y: (c.Size.Y - ln.length) * ay}
+		x += ln.thick
+	}
+	c.Size.X = x
+
+	j := 0
+	i = 0
+	y := 0.0
+	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
+		if k.flags&flagDecoration > 0 {
+			return
+		}
+		if j+1 < len(lines) && i >= lines[j+1].l {
+			j++
+			y = 0
+		}
+		i++
+		if len(lines) == 0 {
+			return
+		}
+		ln := &lines[j]
+		stretch := false
+
+		beginaxis(k)
+		e := stretchof(k)
+		if i > ln.r {
+			// Collapse the space at the line break.
+			k.Size.Y = 0
+			k.p = point{X: ln.pos.X, Y: ln.pos.Y + y}
+			endaxis(k)
+			return
+		}
+		if e.Y > 0 {
+			k.Size.Y = ln.free / ln.props * e.Y
+			k.l.Y = k.Size.Y
+			stretch = true
+		}
+		if e.X > 0 {
+			k.Size.X = ln.thick
+			k.l.X = k.Size.X
+			stretch = true
+		}
+		endaxis(k)
+
+		if stretch {
+			wo.apply(c, k)
+		}
+
+		beginaxis(k)
+		// Stop laying out kids if we're clipped out of limit, unless they are scrolled into it.
+		if ln.pos.X > c.l.X && c.idx == nil {
+			k.flags |= flagBreakIteration
+		}
+		k.p.X = ln.pos.X + max(0, ln.thick-k.Size.X)*ax
+		k.p.Y = ln.pos.Y + y
+		y += k.Size.Y
+		endaxis(k)
+	})
+	endaxis(c)
+
+	decorate(wo, c, h, len(lines), func(d *Sorm, j int) {
+		ln := lines[j]
+		d.Size = point{X: ln.thick, Y: ln.length}
+		d.p = ln.pos
+	})
+}
+
+// decorated reports if s is a line-breaking premodifier with line decorations.
+func (s *Sorm) decorated() bool {
+	switch s.tag {
+	case tagHwords, tagVwords, tagHknuth, tagVknuth:
+		return s.key != nil
+	}
+	return false
+}
+
+// decorate makes the line decorations of a compound, if it has any, and lays every one
+// of them out with place. Decorations are made in the current pool once per layout pass
+// and are reused by the next passes while the number of lines is the same.
+// Their premodifier keeps them like a materialized Sequence: kidsl and kidsr are all
+// of their Sorms, presl and presr are the decorations themselves.
+func decorate(wo *World, c *Sorm, h bool, n int, place func(d *Sorm, line int)) {
+	var m *Sorm
+	for _, p := range c.pres(wo) {
+		if p.decorated() {
+			m = p
+		}
+	}
+	if m == nil {
+		return
+	}
+	if m.flags&flagSequenceSaved == 0 || m.presr-m.presl != n {
+		perline := m.key.(func() *Sorm)
+		reall := len(wo.pool)
+		roots := wo.tmpalloc(n)
+		for i := range roots {
+			roots[i] = perline()
+			roots[i].flags |= flagDecoration
+		}
+		l, r := wo.alloc(n)
+		copy(wo.pool[l:r], roots)
+		m.kidsl, m.kidsr = reall, r
+		m.presl, m.presr = l, r
+		m.flags |= flagSequenceSaved
+	}
+	c.flags |= flagDecorated
+
+	beginaxis, endaxis := axis(h)
+	for j, d := range wo.pool[m.presl:m.presr] {
+		wo.prepasskid(c, d)
+		wo.divide(d)
+		beginaxis(d)
+		place(d, j)
+		d.l = d.Size
+		sz, p := d.Size, d.p
+		endaxis(d)
+		wo.apply(c, d)
+		beginaxis(d)
+		d.Size, d.p = sz, p
+		endaxis(d)
+	}
+}
+
 func (wo *World) prepass(_ *Sorm, c *Sorm, one bool) {
 	if c.tag != 0 {
 		return
@@ -1257,35 +1803,44 @@ func (wo *World) prepass(_ *Sorm, c *Sorm, one bool) {
 		}
 	}
 
+	// Kids of scrolled compounds are prepassed by scrollaligner when they are materialized.
+	if c.lazy() {
+		return
+	}
+
 	// When wo.cropping == 0, this is the first tree iteration in a frame.
 	c.kidsiter(wo, kiargs{firstloop: one}, func(k *Sorm) {
-		// Cascade matrices and some flags
-		k.m = c.m
-		k.flags |= c.flags & flagNoround
-		k.flags |= c.flags & flagRound
-		wo.prepass(c, k, false)
-		if wo.cropping == 0 {
-			if c.flags&flagCrop == 0 && k.flags&flagCrop == 0 {
-				k.flags |= flagNotCropped
-			}
+		wo.prepasskid(c, k)
+	})
+}
+
+func (wo *World) prepasskid(c, k *Sorm) {
+	// Cascade matrices and some flags
+	k.m = c.m
+	k.flags |= c.flags & flagNoround
+	k.flags |= c.flags & flagRound
+	wo.prepass(c, k, false)
+	if wo.cropping == 0 {
+		if c.flags&flagCrop == 0 && k.flags&flagCrop == 0 {
+			k.flags |= flagNotCropped
 		}
-		// Resolve sprite text widths based on a real font size
-		// TODO Broken, probably because of incorrect scaling with matrices
-		// TODO Vertical
-		// TODO flagNonlinear — set the size of an element only after setting up the matrix.
-		//	Equation is the another type of element with this property.
-		if k.tag == tagText {
-			s := k
-			wo.Vgo.SetFontFaceID(s.fontid)
-			wo.Vgo.SetFontSize(k.Size.Y)
-			_, abcd := wo.Vgo.TextBounds(0, 0, s.key.([]rune))
-			_, space := wo.Vgo.TextBounds(0, 0, []rune{' '})
-			s.Size.X = abcd.Dx() - space.Dx()
-			if s.Size.X < 0 {
-				s.Size.X = 0
-			}
+	}
+	// Resolve sprite text widths based on a real font size
+	// TODO Broken, probably because of incorrect scaling with matrices
+	// TODO Vertical
+	// TODO flagNonlinear — set the size of an element only after setting up the matrix.
+	//	Equation is the another type of element with this property.
+	if k.tag == tagText {
+		s := k
+		wo.Vgo.SetFontFaceID(s.fontid)
+		wo.Vgo.SetFontSize(k.Size.Y)
+		_, abcd := wo.Vgo.TextBounds(0, 0, s.key.([]rune))
+		_, space := wo.Vgo.TextBounds(0, 0, []rune{' '})
+		s.Size.X = abcd.Dx() - space.Dx()
+		if s.Size.X < 0 {
+			s.Size.X = 0
 		}
-	})
+	}
 }
 
 func (wo *World) apply(p *Sorm, c *Sorm) {
@@ -1305,36 +1860,24 @@ func (wo *World) apply(p *Sorm, c *Sorm) {
 	if c.flags&flagCrop > 0 {
 		c.cropr = geom.Rect(0, 0, c.l.X, c.l.Y)
 	}
-	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
-		// NOTE Aligner is called after these assignments.
-		// 	So this can't influence limits at later stages.
-		k.l.X = c.l.X
-		k.l.Y = c.l.Y
-		// Inherit crop.
-		// TODO This may be in the first iteration.
-		if c.flags&flagCrop > 0 {
-			k.cropi = c.i
-		} else {
-			k.cropi = c.cropi
-		}
-
-		// Apply scale.
-		ns := k.m.ApplyPt(geom.Pt(k.Size.X, k.Size.Y))
-		ims := k.m.ApplyPt(geom.Pt(k.add.X, k.add.Y))
-		// Don't scale stretch coefficients.
-		k.Size.X = cond(k.Size.X >= 0, ns.X, k.Size.X)
-		k.Size.Y = cond(k.Size.Y >= 0, ns.Y, k.Size.Y)
-		// But scale imaginaries.
-		k.add.X = ims.X
-		k.add.Y = ims.Y
-
-		// Process only kids which sizes are known first.
-		if k.Size.X >= 0 && k.Size.Y >= 0 {
-			wo.apply(c, k)
+	// Kids of scrolled compounds are applied by scrollaligner when they are materialized.
+	if !c.lazy() {
+		wrapped := false
+		c.kidsiter(wo, kiargs{}, func(k *Sorm) {
+			wo.applykid(c, k)
+			wrapped = wrapped || k.tag == tagParagraph
+		})
+		// Dividers have counted the sizes of paragraphs before they were wrapped.
+		if wrapped {
+			divider(wo, c)
 		}
-	})
+	}
 
+	wo.equalize(c)
 	alignerActions[c.aligner](wo, c)
+	if c.idx != nil && !c.lazy() {
+		scrollshift(wo, c)
+	}
 	if c.flags&flagCrop > 0 {
 		c.Size = c.l
 	}
@@ -1360,6 +1903,141 @@ func (wo *World) apply(p *Sorm, c *Sorm) {
 		c.Size.X = min(c.Size.X, c.l.X)
 		c.Size.Y = min(c.Size.Y, c.l.Y)
 	}
+	wo.equalize(c)
+}
+
+// applykid passes the limit, the crop and the scale of c to k,
+// and applies k if its size is known.
+func (wo *World) applykid(c, k *Sorm) {
+	// NOTE Aligner is called after these assignments.
+	// 	So this can't influence limits at later stages.
+	k.l.X = c.l.X
+	k.l.Y = c.l.Y
+	// Inherit crop.
+	// TODO This may be in the first iteration.
+	if c.flags&flagCrop > 0 {
+		k.cropi = c.i
+	} else {
+		k.cropi = c.cropi
+	}
+
+	// Apply scale.
+	ns := k.m.ApplyPt(geom.Pt(k.Size.X, k.Size.Y))
+	ims := k.m.ApplyPt(geom.Pt(k.add.X, k.add.Y))
+	// Don't scale stretch coefficients.
+	k.Size.X = cond(k.Size.X >= 0, ns.X, k.Size.X)
+	k.Size.Y = cond(k.Size.Y >= 0, ns.Y, k.Size.Y)
+	// But scale imaginaries.
+	k.add.X = ims.X
+	k.add.Y = ims.Y
+
+	// Paragraph sizes are known only with a limit.
+	if k.tag == tagParagraph {
+		wo.wrap(k)
+	}
+
+	// Process only kids which sizes are known first.
+	if k.Size.X >= 0 && k.Size.Y >= 0 {
+		wo.apply(c, k)
+	}
+}
+
+// equalize makes a compound as large as the largest compound with the same Eqkey.
+// It is called before the aligner, so stretchy kids of a shrunk compound fill the
+// equalized size, and after everything else.
+func (wo *World) equalize(c *Sorm) {
+	if wo.eqpass != 2 || c.eq == [2]Eqkey{} {
+		return
+	}
+	if k := c.eq[0]; k != 0 {
+		c.Size.X = max(c.Size.X, wo.eqsizes[k].X)
+	}
+	if k := c.eq[1]; k != 0 {
+		c.Size.Y = max(c.Size.Y, wo.eqsizes[k].Y)
+	}
+}
+
+// measure records natural sizes of compounds with Eqkeys after the measuring layout.
+func (wo *World) measure(pool []*Sorm) {
+	for _, c := range pool {
+		if c.tag != 0 || c.eq == [2]Eqkey{} {
+			continue
+		}
+		if k := c.eq[0]; k != 0 {
+			sz := wo.eqsizes[k]
+			sz.X = max(sz.X, c.Size.X)
+			wo.eqsizes[k] = sz
+		}
+		if k := c.eq[1]; k != 0 {
+			sz := wo.eqsizes[k]
+			sz.Y = max(sz.Y, c.Size.Y)
+			wo.eqsizes[k] = sz
+		}
+	}
+}
+
+// checkpoint saves the tree before the layout and returns the function that restores it.
+// Indices changed by scrollaligner are restored too, see (*World).saveindex.
+// Sequences are materialized again after restoring, so their Get must return the same elements
+// when it is called twice in a frame.
+func (wo *World) checkpoint(pool []*Sorm) (restore func()) {
+	wo.eqsnap = wo.eqsnap[:0]
+	for _, s := range pool {
+		wo.eqsnap = append(wo.eqsnap, *s)
+	}
+	nzorder, npool, naux, ntmp, ncropped, nsinks, nlog := len(wo.zorder), len(wo.pool), len(wo.auxpool), len(wo.tmp), len(wo.cropped), len(wo.sinks), len(wo.Vgo.Log)
+	nextn, auxn := wo.nextn, wo.auxn
+	return func() {
+		for i, s := range pool {
+			*s = wo.eqsnap[i]
+		}
+		wo.zorder = wo.zorder[:nzorder]
+		wo.pool = wo.pool[:npool]
+		wo.auxpool = wo.auxpool[:naux]
+		wo.tmp = wo.tmp[:ntmp]
+		wo.cropped = wo.cropped[:ncropped]
+		wo.sinks = wo.sinks[:nsinks]
+		wo.Vgo.Log = wo.Vgo.Log[:nlog]
+		wo.nextn, wo.auxn = nextn, auxn
+		for idx, v := range wo.eqidx {
+			*idx = v
+		}
+		clear(wo.eqidx)
+	}
+}
+
+// saveindex saves idx before it is changed by the measuring layout.
+func (wo *World) saveindex(idx *Index) {
+	if wo.eqpass != 1 {
+		return
+	}
+	if _, ok := wo.eqidx[idx]; !ok {
+		wo.eqidx[idx] = *idx
+	}
+}
+
+func divider(wo *World, c *Sorm) {
+	if c.idx != nil {
+		// Scrolled compounds don't stretch kids along the scrolled axis.
+		return
+	}
+	switch c.aligner {
+	case alignerVfollow:
+		followdivider(wo, c, false)
+	case alignerHfollow:
+		followdivider(wo, c, true)
+	}
+}
+
+// divide calls dividers of a subtree made during the layout, bottom-up.
+func (wo *World) divide(c *Sorm) {
+	if c.tag != 0 || c.lazy() {
+		return
+	}
+	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
+		wo.divide(k)
+	})
+	divider(wo, c)
 }
 
 func (wo *World) layout(pool []*Sorm, root ...*Sorm) {
@@ -1368,12 +2046,7 @@ func (wo *World) layout(pool []*Sorm, root ...*Sorm) {
 		wo.prepass(nil, root[i], i == 0 && wo.cropping == 0)
 		wo.bottombreadthiter(pool, func(k, _ *Sorm) {
 			if k.flags&flagNotCropped > 0 {
-				switch k.aligner {
-				case alignerVfollow:
-					followdivider(wo, k, false)
-				case alignerHfollow:
-					followdivider(wo, k, true)
-				}
+				divider(wo, k)
 			}
 		})
 
@@ -1386,6 +2059,11 @@ func (wo *World) layout(pool []*Sorm, root ...*Sorm) {
 	// coordinates till the very end.
 	wo.topbreadthiter(pool, func(c, efc *Sorm) {
 		c.kidsiter(wo, kiargs{}, func(k *Sorm) {
+			// Replacements of Penalties are hidden by aligners.
+			if k.flags&flagReplacement == 0 {
+				k.flags &^= flagHidden
+			}
+			k.flags |= efc.flags & flagHidden
 			k.p = k.p.Add(efc.p)
 			k.cropr = k.cropr.Add(efc.p)
 			if k.fill == (nanovgo.Paint{}) {
@@ -1408,12 +2086,24 @@ func (wo *World) layout(pool []*Sorm, root ...*Sorm) {
 	})
 }
 
+func (wo *World) layoutall(pool []*Sorm) {
+	wo.cropping = 0
+	wo.layout(pool, *last(pool))
+	wo.cropping = 1
+	wo.layout(pool, wo.cropped...)
+	wo.cropping = 2
+}
+
 func (wo *World) windowDevelop() {
 	if wo.BeforeVgo != nil {
 		wo.BeforeVgo()
 	}
 	wo.BeforeVgo = nil
 	_ = wo.Vgo.EndFrame()
+	for _, rer := range wo.exports {
+		rer.Run(wo.Vgo)
+	}
+	wo.exports = wo.exports[:0]
 	if wo.Events.tempcur == 0 {
 		// Retain if was not changed
 		wo.rer.Run(wo.Vgo)
@@ -1458,11 +2148,19 @@ func (wo *World) Develop() []RenderOp {
 		s.i = i
 	}
 
-	wo.cropping = 0
-	wo.layout(pool, *last(pool))
-	wo.cropping = 1
-	wo.layout(pool, wo.cropped...)
-	wo.cropping = 2
+	if wo.eqpass > 0 {
+		// Compounds with Hequal and Vequal depend on sizes of each other, so the tree
+		// is laid out twice: first to measure them, then for real.
+		restore := wo.checkpoint(pool)
+		wo.layoutall(pool)
+		wo.measure(pool)
+		wo.measure(wo.auxpool)
+		restore()
+		wo.eqpass = 2
+	}
+	wo.layoutall(pool)
+	// Sequences were materialized by the layout.
+	auxpool = wo.auxpool
 
 	// Print tree for debug. Do it before sorting.
 	if wo.f1 {
@@ -1487,13 +2185,26 @@ func (wo *World) Develop() []RenderOp {
 	slices.SortFunc(pool, func(a, b *Sorm) int {
 		return a.z - b.z
 	})
+	for _, s := range pool {
+		if s.tag == tagSequence {
+			slices.SortFunc(auxpool[s.kidsl:s.kidsr], func(a, b *Sorm) int {
+				return a.z2 - b.z2
+			})
+		}
+	}
 	// After this point, (*Sorm).kidsiter won't work because indices are broken.
 
 skiplayout:
 	// Apply conditional paints, match drag-and-drop events, handle scrolls.
-	for i := len(pool) - 1; i >= 0; i-- {
-		s := pool[i]
+	// Shapes that match events are saved to the hitmap for events of the next frame.
+	// Elements of Sequences are matched right above their Sequence, like they are drawn.
+	wo.Events.hitmap = wo.Events.hitmap[:0]
+	z := len(pool) + len(auxpool)
+	match := func(s *Sorm) {
 		r := geom.Rect(s.p.X, s.p.Y, s.p.X+s.Size.X, s.p.Y+s.Size.Y)
+		if (s.cond != nil || s.idx != nil || s.sinkid > 0 || s.flags&(flagSource|flagFocusable) > 0) && !r.Empty() {
+			wo.Events.hitmap = append(wo.Events.hitmap, hitrect{r, s.hitid()})
+		}
 		if s.condfillstroke != nil {
 			s.fill, s.stroke = s.condfillstroke(r)
 		}
@@ -1503,7 +2214,9 @@ skiplayout:
 		if s.condstroke != nil {
 			s.stroke = s.condstroke(r)
 		}
-		m := wo.Events.In(r).WithZ(i + 1)
+		m := wo.Events.In(r).WithZ(z)
+		z--
+		m.hit = s.hitid()
 		if s.flags&flagSource > 0 {
 			if m.Match(`Click(1):in`) {
 				wo.drag = s.key
@@ -1519,10 +2232,40 @@ skiplayout:
 				wo.drag = nil
 			}
 		}
-		if s.idx != nil {
-			if m.Match(`Scroll:in`) {
-				// s.idx.I
+		// Hscroll and Vscroll modifiers keep the Index too, but only their compound is scrolled.
+		if s.idx != nil && s.tag == tagCompound {
+			wo.scroll(s, m)
+		}
+	}
+
+	// Line decorations are matched right above their premodifier, see decorate.
+	var matchall func(pool, home []*Sorm)
+	matchall = func(pool, home []*Sorm) {
+		for i := len(pool) - 1; i >= 0; i-- {
+			s := pool[i]
+			switch {
+			case s.tag == tagSequence:
+				matchall(auxpool[s.kidsl:s.kidsr], auxpool)
+			case s.decorated() && s.flags&flagSequenceSaved > 0:
+				matchall(home[s.kidsl:s.kidsr], home)
 			}
+			match(s)
+		}
+	}
+	matchall(pool, wo.pool)
+
+	if wo.Events.hitids == nil {
+		wo.Events.hitids = map[hitid]bool{}
+	}
+	clear(wo.Events.hitids)
+	for _, h := range wo.Events.hitmap {
+		wo.Events.hitids[h.id] = true
+	}
+
+	wo.focusables = wo.focusables[:0]
+	for _, s := range pool {
+		if s.flags&flagFocusable > 0 && s.key != nil && s.Size != (point{}) {
+			wo.focusables = append(wo.focusables, s.key)
 		}
 	}
 
@@ -1532,9 +2275,8 @@ skiplayout:
 	}
 
 	// Draw.
-	// FIXME auxpool is not sorted.
 	wo.bottombreadthiter(pool, func(c, _ *Sorm) {
-		if c.tag <= 0 {
+		if c.tag <= 0 || c.flags&flagHidden > 0 {
 			return
 		}
 
@@ -1695,6 +2437,18 @@ skiplayout:
 		}
 	}
 
+	for k, v := range wo.tiles {
+		v.life--
+		if v.life > 0 {
+			wo.tiles[k] = v
+			continue
+		}
+		if v.img != 0 {
+			wo.Vgo.DeleteImage(v.img)
+		}
+		delete(wo.tiles, k)
+	}
+
 	return wo.Vgo.Log
 }
 
@@ -1712,7 +2466,10 @@ func (wo *World) Next() bool {
 	// NOTE Next()/Develop() is easier to debug
 	wo.Vgo.Log = wo.Vgo.Log[:0]
 	wo.MatchCount = 0
+	wo.eqpass = 0
+	clear(wo.eqsizes)
 	wo.Events.next()
+	wo.activate()
 	ok, w, h, sc := wo.wer.Next(wo.Events)
 	if !ok {
 		return false
@@ -1729,6 +2486,25 @@ func (wo *World) Next() bool {
 	return true
 }
 
+// Page sets the size of the current frame to the page of wmm×hmm millimetres,
+// taking a unit as a typographic point.
+// The tree is then laid out as a page of a document and the frame can be exported
+// as a page, e.g. with the PDF renderer.
+// Page must be called between Next and Develop.
+func (wo *World) Page(wmm, hmm float64) {
+	if wo.Vgo.state == 0 {
+		panic(`contraption: Page must be called between Next and Develop`)
+	}
+	w, h := mmtopt(wmm), mmtopt(hmm)
+	wo.Wwin, wo.Hwin = w, h
+	wo.Events.Viewport = geom.Pt(w, h)
+
+	// Frame size is integer, so the exact size of the page is passed along.
+	b := &wo.Vgo.Log[0]
+	b.Iargs[0], b.Iargs[1] = int(math.Ceil(w)), int(math.Ceil(h))
+	b.Args[1], b.Args[2] = w, h
+}
+
 func (wo *World) recorder() {
 	vgo := wo.Vgo
 
@@ -1804,28 +2580,6 @@ func sormp(wo *World, s *Sorm, tab int) {
 	})
 }
 
-// Activator — это любой объект, на котором может быть сконцентрирован
-// фокус ввода.
-// Этот объект может обрабатывать свои события внутри метода Activate.
-// В системе есть какое-то глобальное поле, которое обозначает
-// текущий сфокусированный объект.
-//
-// Activate возвращает Silence, когда объект не среагировал на событие,
-// Ack, когда среагировал и Deactivate, когда объект сбросил с
-// себя фокус.
-//
-// Activator временный и умирает после смены фокуса.
-type Activator interface {
-	Activate(events *Events) (action Symbol)
-}
-
-// ActivatorPainter — это Activator, который может рисовать себя
-// во время Drag.
-type ActivatorPainter interface {
-	Activator
-	Paint(wo *World) Sorm
-}
-
 // Config is the starting setup for Contraption.
 type Config struct {
 	// Default window frame after launch.
@@ -1858,7 +2612,10 @@ func New(wer Windower, rer Renderer, config Config) (wo *World) {
 	wo.sinks = make([]func(any), 1)
 	wo.keys = map[any]*labelt{}
 	wo.images = map[io.Reader]imagestruct{}
+	wo.tiles = map[dvtile]dvtexture{}
 	wo.drags = map[reflect.Type]func(interval [2]geom.Point, drag any) *Sorm{}
+	wo.eqsizes = map[Eqkey]point{}
+	wo.eqidx = map[*Index]Index{}
 	wo.alloc = wo.allocmain
 	return wo
 }
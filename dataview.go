@@ -0,0 +1,299 @@
+package contraption
+
+import (
+	"image"
+	"math"
+
+	"github.com/neputevshina/contraption/nanovgo"
+	"github.com/neputevshina/geom"
+)
+
+// Parameters of data views.
+const (
+	pyramidFactor = 8   // Samples in a block of the next level of a Pyramid
+	dataviewZoom  = 1.1 // Zoom by a step of the wheel
+	dataviewPan   = 0.1 // Pan by a step of the wheel, in viewports
+)
+
+// Dataview is a state of a view of large data, see (*World).Waveform and (*World).Tilemap.
+type Dataview struct {
+	// View is the range of data coordinates seen. It is changed by zooming and panning.
+	View geom.Rectangle
+	// If Bounds is not empty, View is kept inside it.
+	Bounds geom.Rectangle
+}
+
+// dvtile is a tile of the level k at x, y of a Tilemap.
+type dvtile struct {
+	dv      *Dataview
+	k, x, y int
+}
+
+type dvtexture struct {
+	img  int // 0 if there is no tile
+	life int // Frames left until it is deleted, see (*World).Develop
+}
+
+// Mark is a Sorm made at a point of data coordinates, like a marker or a label.
+// Its top left corner is at the point. Make is called only if the point is seen.
+type Mark struct {
+	At   geom.Point
+	Make func() *Sorm
+}
+
+// Pyramid keeps minimums and maximums of a signal at levels of detail, so a waveform of any length
+// is drawn in time proportional to its width on the screen. Levels are computed when they are needed.
+type Pyramid struct {
+	n      int
+	at     func(i int) float64
+	levels [][][2]float64 // levels[k] has a pair for every pyramidFactor^k samples, levels[0] is not used
+}
+
+// NewPyramid returns a Pyramid of n samples, where at returns the i-th sample.
+func NewPyramid(n int, at func(i int) float64) *Pyramid {
+	return &Pyramid{n: n, at: at, levels: [][][2]float64{nil}}
+}
+
+// Len returns the count of samples.
+func (p *Pyramid) Len() int {
+	return p.n
+}
+
+// block returns the size of a block of the level k.
+func (p *Pyramid) block(k int) int {
+	b := 1
+	for ; k > 0; k-- {
+		b *= pyramidFactor
+	}
+	return b
+}
+
+// level returns the level k, computing it if it is needed.
+func (p *Pyramid) level(k int) [][2]float64 {
+	for len(p.levels) <= k {
+		j := len(p.levels)
+		b := p.block(j)
+		l := make([][2]float64, (p.n+b-1)/b)
+		for i := range l {
+			l[i][0], l[i][1] = p.minmax(j-1, i*b, min(p.n, (i+1)*b))
+		}
+		p.levels = append(p.levels, l)
+	}
+	return p.levels[k]
+}
+
+// Minmax returns the minimum and the maximum of samples from i to j.
+func (p *Pyramid) Minmax(i, j int) (lo, hi float64) {
+	i, j = max(0, i), min(p.n, j)
+	k := 0
+	for p.block(k+1)*2 <= j-i {
+		k++
+	}
+	return p.minmax(k, i, j)
+}
+
+// minmax is Minmax using levels up to k.
+func (p *Pyramid) minmax(k, i, j int) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	if i >= j {
+		return
+	}
+	if k == 0 {
+		for ; i < j; i++ {
+			v := p.at(i)
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		return
+	}
+	b := p.block(k)
+	l, r := (i+b-1)/b, j/b
+	if l >= r {
+		return p.minmax(k-1, i, j)
+	}
+	for _, m := range p.level(k)[l:r] {
+		lo, hi = min(lo, m[0]), max(hi, m[1])
+	}
+	a, z := p.minmax(k-1, i, l*b)
+	lo, hi = min(lo, a), max(hi, z)
+	a, z = p.minmax(k-1, r*b, j)
+	return min(lo, a), max(hi, z)
+}
+
+// Tiles is a 2D source of square images at levels of detail, like a map or a spectrogram.
+// A tile of the level k at x, y covers Tilesize()×2^k data units from (x, y)×Tilesize()×2^k,
+// so at the level 0 there is a pixel for every data unit.
+type Tiles interface {
+	Tilesize() int
+	Levels() int
+	// Tile returns nil if there is no such tile.
+	Tile(k, x, y int) image.Image
+}
+
+// Waveform returns a view of the signal p, with samples along X and values along Y, upwards.
+// Only the seen range is drawn, each pixel column shows the minimum and maximum of its samples.
+//
+// Wheel zooms the samples around the cursor, horizontal wheel and dragging with the middle button pan them.
+// Fill paints the waveform, Stroke paints it when there are less samples than pixels.
+func (wo *World) Waveform(dv *Dataview, p *Pyramid, marks ...Mark) *Sorm {
+	return wo.dataview(dv, false, func(vgo *Context, r geom.Rectangle) {
+		v := dv.View
+		w, h := r.Dx(), r.Dy()
+		if w <= 0 || v.Dx() <= 0 || v.Dy() <= 0 {
+			return
+		}
+		y := func(s float64) float64 { return (v.Max.Y - s) / v.Dy() * h }
+		spp := v.Dx() / w
+		vgo.BeginPath()
+		if spp < 1 {
+			// Zoomed in, so draw a line through samples.
+			first := true
+			for i := max(0, int(math.Floor(v.Min.X))); i < min(p.n, int(math.Ceil(v.Max.X))+1); i++ {
+				x := (float64(i) - v.Min.X) / spp
+				if first {
+					vgo.MoveTo(x, y(p.at(i)))
+					first = false
+				} else {
+					vgo.LineTo(x, y(p.at(i)))
+				}
+			}
+			vgo.SetStrokeWidth(1)
+			vgo.Stroke()
+			return
+		}
+		for px := 0.0; px < w; px++ {
+			lo, hi := p.Minmax(int(math.Floor(v.Min.X+px*spp)), int(math.Ceil(v.Min.X+(px+1)*spp)))
+			if lo > hi {
+				continue
+			}
+			top := y(hi)
+			vgo.Rect(px, top, 1, max(1, y(lo)-top))
+		}
+		vgo.Fill()
+	}, func(at geom.Point) (fx, fy float64) {
+		return (at.X - dv.View.Min.X) / dv.View.Dx(), (dv.View.Max.Y - at.Y) / dv.View.Dy()
+	}, marks)
+}
+
+// Tilemap returns a view of the tiled source t, with Y downwards like in images.
+// Only seen tiles are drawn, from the level that has the closest resolution. Their textures
+// are deleted by the World when they are not seen for two frames, even if the Tilemap is not made anymore.
+//
+// Wheel zooms around the cursor, horizontal wheel and dragging with the middle button pan.
+func (wo *World) Tilemap(dv *Dataview, t Tiles, marks ...Mark) *Sorm {
+	return wo.dataview(dv, true, func(vgo *Context, r geom.Rectangle) {
+		v := dv.View
+		w, h := r.Dx(), r.Dy()
+		if w <= 0 || h <= 0 || v.Dx() <= 0 || v.Dy() <= 0 {
+			return
+		}
+		upp := v.Dx() / w
+		k := clamp(0, int(math.Floor(math.Log2(upp))), max(0, t.Levels()-1))
+		span := float64(t.Tilesize() << k)
+		for ty := int(math.Floor(v.Min.Y / span)); float64(ty)*span < v.Max.Y; ty++ {
+			for tx := int(math.Floor(v.Min.X / span)); float64(tx)*span < v.Max.X; tx++ {
+				key := dvtile{dv, k, tx, ty}
+				u, ok := wo.tiles[key]
+				if !ok {
+					if img := t.Tile(k, tx, ty); img != nil {
+						u.img = vgo.CreateImageFromGoImage(0, img)
+					}
+				}
+				u.life = 3
+				wo.tiles[key] = u
+				if u.img == 0 {
+					continue
+				}
+				x0 := (float64(tx)*span - v.Min.X) / v.Dx() * w
+				y0 := (float64(ty)*span - v.Min.Y) / v.Dy() * h
+				sw, sh := span/v.Dx()*w, span/v.Dy()*h
+				vgo.BeginPath()
+				vgo.SetFillPaint(nanovgo.ImagePattern(float32(x0), float32(y0), float32(sw), float32(sh), 0, u.img, 1))
+				vgo.Rect(x0, y0, sw, sh)
+				vgo.Fill()
+			}
+		}
+	}, func(at geom.Point) (fx, fy float64) {
+		return (at.X - dv.View.Min.X) / dv.View.Dx(), (at.Y - dv.View.Min.Y) / dv.View.Dy()
+	}, marks)
+}
+
+// dataview makes a view that draws with draw in local coordinates and places marks at fractions of its size.
+func (wo *World) dataview(dv *Dataview, zoomy bool, draw func(vgo *Context, r geom.Rectangle), frac func(at geom.Point) (fx, fy float64), marks []Mark) *Sorm {
+	args := []*Sorm{
+		wo.Crop(),
+		wo.Cond(func(m Matcher) {
+			dv.handle(wo, m, zoomy)
+		}),
+		wo.Canvas(-1, -1, func(vgo *Context, _ geom.Geom, r geom.Rectangle) {
+			draw(vgo, r)
+		}),
+	}
+	for _, mk := range marks {
+		fx, fy := frac(mk.At)
+		if !(fx >= 0 && fx <= 1 && fy >= 0 && fy <= 1) {
+			continue
+		}
+		// The mark hangs from a point of zero size aligned inside the whole view.
+		args = append(args, wo.Compound(
+			wo.Halign(fx),
+			wo.Valign(fy),
+			wo.Void(-1, -1),
+			wo.Compound(
+				wo.Void(0, 0).Override(),
+				mk.Make())))
+	}
+	return wo.Compound(args...)
+}
+
+// handle zooms and pans the view.
+func (dv *Dataview) handle(wo *World, m Matcher, zoomy bool) {
+	ev := wo.Events.Trace[0]
+	r := m.Rect()
+	if r.Dx() <= 0 || r.Dy() <= 0 {
+		return
+	}
+	v := &dv.View
+	// Data coordinates of the cursor.
+	at := geom.Pt(
+		v.Min.X+(ev.Pt.X-r.Min.X)/r.Dx()*v.Dx(),
+		v.Min.Y+(ev.Pt.Y-r.Min.Y)/r.Dy()*v.Dy())
+	switch {
+	case m.Match(`Scroll:in`):
+		f := math.Pow(dataviewZoom, float64(ev.E.(Scroll)))
+		v.Min.X, v.Max.X = at.X-(at.X-v.Min.X)*f, at.X+(v.Max.X-at.X)*f
+		if zoomy {
+			v.Min.Y, v.Max.Y = at.Y-(at.Y-v.Min.Y)*f, at.Y+(v.Max.Y-at.Y)*f
+		}
+	case m.Match(`Sweep:in`):
+		d := float64(ev.E.(Sweep)) * dataviewPan * v.Dx()
+		v.Min.X, v.Max.X = v.Min.X+d, v.Max.X+d
+	case m.Match(`Hover !Unclick(2)* Click(2):in`):
+		d := wo.Events.Trace[1].Pt.Sub(ev.Pt)
+		d.X *= v.Dx() / r.Dx()
+		d.Y *= v.Dy() / r.Dy()
+		if !zoomy {
+			d.Y = 0
+		}
+		*v = v.Add(d)
+	default:
+		return
+	}
+	dv.clamp()
+	// Marks are placed on the next frame.
+	wo.Events.animate()
+}
+
+// clamp keeps the View inside the Bounds.
+func (dv *Dataview) clamp() {
+	b, v := dv.Bounds, &dv.View
+	if b.Empty() {
+		return
+	}
+	if v.Dx() > b.Dx() {
+		v.Min.X, v.Max.X = b.Min.X, b.Max.X
+	}
+	if v.Dy() > b.Dy() {
+		v.Min.Y, v.Max.Y = b.Min.Y, b.Max.Y
+	}
+	*v = v.Add(geom.Pt(max(0, b.Min.X-v.Min.X)+min(0, b.Max.X-v.Max.X), max(0, b.Min.Y-v.Min.Y)+min(0, b.Max.Y-v.Max.Y)))
+}
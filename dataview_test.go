@@ -0,0 +1,104 @@
+package contraption
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/neputevshina/geom"
+)
+
+func TestPyramidMinmax(t *testing.T) {
+	n := 1000
+	at := func(i int) float64 { return float64(i * 7919 % 1009) }
+	p := NewPyramid(n, at)
+	brute := func(i, j int) (lo, hi float64) {
+		lo, hi = math.Inf(1), math.Inf(-1)
+		for k := max(0, i); k < min(n, j); k++ {
+			lo, hi = min(lo, at(k)), max(hi, at(k))
+		}
+		return
+	}
+	for _, tt := range []struct {
+		name string
+		i, j int
+	}{
+		{"empty", 5, 5},
+		{"reversed", 9, 3},
+		{"sample", 5, 6},
+		{"across a block edge", 7, 9},
+		{"inside a block of the level 1", 17, 23},
+		{"blocks of the level 1 with remainders", 7, 30},
+		{"remainder inside a block of the level 1", 61, 200},
+		{"aligned blocks", 64, 576},
+		{"remainders at every level", 1, 999},
+		{"last partial block", 900, 1000},
+		{"clamped", -20, 2000},
+	} {
+		lo, hi := p.Minmax(tt.i, tt.j)
+		wlo, whi := brute(tt.i, tt.j)
+		if lo != wlo || hi != whi {
+			t.Errorf("%s: Minmax(%d, %d) is %g, %g, want %g, %g", tt.name, tt.i, tt.j, lo, hi, wlo, whi)
+		}
+	}
+	for i := 0; i < n; i += 37 {
+		for j := i; j <= n; j += 53 {
+			lo, hi := p.Minmax(i, j)
+			wlo, whi := brute(i, j)
+			if lo != wlo || hi != whi {
+				t.Fatalf("Minmax(%d, %d) is %g, %g, want %g, %g", i, j, lo, hi, wlo, whi)
+			}
+		}
+	}
+}
+
+func TestDataviewClamp(t *testing.T) {
+	b := geom.Rect(0, 0, 100, 50)
+	for _, tt := range []struct {
+		name         string
+		bounds, view geom.Rectangle
+		want         geom.Rectangle
+	}{
+		{"no bounds", geom.Rectangle{}, geom.Rect(-10, -10, 10, 10), geom.Rect(-10, -10, 10, 10)},
+		{"inside", b, geom.Rect(10, 10, 20, 20), geom.Rect(10, 10, 20, 20)},
+		{"before", b, geom.Rect(-10, -5, 10, 5), geom.Rect(0, 0, 20, 10)},
+		{"after", b, geom.Rect(90, 45, 110, 55), geom.Rect(80, 40, 100, 50)},
+		{"wider", b, geom.Rect(-10, 10, 200, 20), geom.Rect(0, 10, 100, 20)},
+		{"larger", b, geom.Rect(-10, -10, 200, 200), b},
+	} {
+		dv := Dataview{View: tt.view, Bounds: tt.bounds}
+		dv.clamp()
+		if dv.View != tt.want {
+			t.Errorf("%s: View is %v, want %v", tt.name, dv.View, tt.want)
+		}
+	}
+}
+
+// grey is Tiles of a single level filled with grey.
+type grey struct{}
+
+func (grey) Tilesize() int { return 10 }
+func (grey) Levels() int   { return 1 }
+func (grey) Tile(k, x, y int) image.Image {
+	return image.NewGray(image.Rect(0, 0, 10, 10))
+}
+
+// TestTilemapTextures checks that textures of a Tilemap are deleted when it is not made anymore.
+func TestTilemapTextures(t *testing.T) {
+	wo := New(stillwindower{}, nullrenderer{}, Config{})
+	dv := &Dataview{View: geom.Rect(0, 0, 40, 40)}
+	for i := 0; i < 5 && wo.Next(); i++ {
+		if i < 2 {
+			wo.Root(wo.Compound(wo.Limit(40, 40), wo.Tilemap(dv, grey{})))
+		} else {
+			wo.Root()
+		}
+		wo.Develop()
+		if i == 1 && len(wo.tiles) != 16 {
+			t.Fatalf("%d textures of tiles, want %d", len(wo.tiles), 16)
+		}
+	}
+	if len(wo.tiles) != 0 {
+		t.Errorf("%d textures of tiles are left, want 0", len(wo.tiles))
+	}
+}
@@ -64,7 +64,8 @@ type RenderFont struct {
 // TODO All transformations must be resolved by Context, not by the backend.
 type Context struct {
 	publicContext
-	state op.Op
+	state  op.Op
+	xforms []nanovgo.TransformMatrix // Saved by Save
 
 	parent *Context
 }
@@ -178,6 +179,8 @@ func (c *Context) BeginFrame(windowWidth, windowHeight int, devicePixelRatio flo
 	})
 	c.devicePxRatio = devicePixelRatio
 	c.state = st
+	c.TransformMatrix = nanovgo.IdentityMatrix()
+	c.xforms = c.xforms[:0]
 }
 func (c *Context) EndFrame() (oldhash [512]byte) {
 	_ = c.add(op.EndFrame, RenderOp{})
@@ -367,23 +370,36 @@ func (c *Context) ResetScissor() {
 	_ = c.add(op.ResetScissor, RenderOp{})
 }
 func (c *Context) ResetTransform() {
+	c.TransformMatrix = nanovgo.IdentityMatrix()
 	_ = c.add(op.ResetTransform, RenderOp{})
 }
 func (c *Context) Restore() {
+	if len(c.xforms) > 0 {
+		c.TransformMatrix = c.xforms[len(c.xforms)-1]
+		c.xforms = c.xforms[:len(c.xforms)-1]
+	}
 	_ = c.add(op.Restore, RenderOp{})
 }
 func (c *Context) Save() {
+	c.xforms = append(c.xforms, c.TransformMatrix)
 	_ = c.add(op.Save, RenderOp{})
 }
 
 // TODO All transformations must be resolved by Context, not by the backend.
+// Until then, Context follows them like backends do to answer CurrentTransform.
 /* Transformation mutators */
 
 func (c *Context) Rotate(angle float64) {
-	_ = c.add(op.Rotate, RenderOp{})
+	c.TransformMatrix = c.TransformMatrix.PreMultiply(nanovgo.RotateMatrix(float32(angle)))
+	_ = c.add(op.Rotate, RenderOp{
+		Args: [10]float64{angle},
+	})
 }
 func (c *Context) Scale(x, y float64) {
-	panic(`unimplemented`)
+	c.TransformMatrix = c.TransformMatrix.PreMultiply(nanovgo.ScaleMatrix(float32(x), float32(y)))
+	_ = c.add(op.Scale, RenderOp{
+		Args: [10]float64{x, y},
+	})
 }
 func (c *Context) Scissor(x, y, w, h float64) {
 	c.assertFrameStarted()
@@ -392,22 +408,35 @@ func (c *Context) Scissor(x, y, w, h float64) {
 	})
 }
 func (c *Context) SkewX(angle float64) {
-	panic(`unimplemented`)
+	c.TransformMatrix = c.TransformMatrix.PreMultiply(nanovgo.SkewXMatrix(float32(angle)))
+	_ = c.add(op.SkewX, RenderOp{
+		Args: [10]float64{angle},
+	})
 }
 func (c *Context) SkewY(angle float64) {
-	panic(`unimplemented`)
+	c.TransformMatrix = c.TransformMatrix.PreMultiply(nanovgo.SkewYMatrix(float32(angle)))
+	_ = c.add(op.SkewY, RenderOp{
+		Args: [10]float64{angle},
+	})
 }
 func (c *Context) SetTransform(t nanovgo.TransformMatrix) {
-	c.TransformMatrix = t
+	c.TransformMatrix = c.TransformMatrix.PreMultiply(t)
 	_ = c.add(op.SetTransform, RenderOp{
 		TransformMatrix: t,
 	})
 }
 func (cx *Context) SetTransformByValue(a, b, c, d, e, f float64) {
-	panic(`unimplemented`)
+	t := nanovgo.TransformMatrix{float32(a), float32(b), float32(c), float32(d), float32(e), float32(f)}
+	cx.TransformMatrix = cx.TransformMatrix.PreMultiply(t)
+	_ = cx.add(op.SetTransformByValue, RenderOp{
+		Args: [10]float64{a, b, c, d, e, f},
+	})
 }
 func (c *Context) Translate(x, y float64) {
-	panic(`unimplemented`)
+	c.TransformMatrix = c.TransformMatrix.PreMultiply(nanovgo.TranslateMatrix(float32(x), float32(y)))
+	_ = c.add(op.Translate, RenderOp{
+		Args: [10]float64{x, y},
+	})
 }
 
 /* Miscellaneous mutators */
@@ -637,3 +666,13 @@ type Windower interface {
 	Next(u *Events) (ok bool, w, h int, scale float64)
 	Develop(u *Events)
 }
+
+// vsyncer is a Windower that can stop waiting for the screen refresh.
+type vsyncer interface {
+	Vsync(on bool)
+}
+
+// clocker is a Windower that keeps its own time, so runs with it are reproducible.
+type clocker interface {
+	Now() time.Time
+}
@@ -0,0 +1,38 @@
+package contraption
+
+import (
+	"testing"
+
+	"github.com/neputevshina/contraption/nanovgo"
+)
+
+func TestCurrentTransform(t *testing.T) {
+	c := newContext()
+	c.BeginFrame(100, 100, 1)
+	c.Translate(10, 20)
+	c.Scale(2, 3)
+	want := nanovgo.TranslateMatrix(10, 20).PreMultiply(nanovgo.ScaleMatrix(2, 3))
+	if got := c.CurrentTransform(); got != want {
+		t.Errorf("after Translate and Scale got %v, want %v", got, want)
+	}
+
+	c.Save()
+	c.Rotate(1)
+	c.SkewX(0.5)
+	c.SkewY(0.25)
+	c.SetTransformByValue(1, 0, 0, 1, 5, 5)
+	rot := want.PreMultiply(nanovgo.RotateMatrix(1)).PreMultiply(nanovgo.SkewXMatrix(0.5)).
+		PreMultiply(nanovgo.SkewYMatrix(0.25)).PreMultiply(nanovgo.TranslateMatrix(5, 5))
+	if got := c.CurrentTransform(); got != rot {
+		t.Errorf("after Rotate, skews and SetTransformByValue got %v, want %v", got, rot)
+	}
+	c.Restore()
+	if got := c.CurrentTransform(); got != want {
+		t.Errorf("after Restore got %v, want %v", got, want)
+	}
+
+	c.ResetTransform()
+	if got := c.CurrentTransform(); got != nanovgo.IdentityMatrix() {
+		t.Errorf("after ResetTransform got %v, want identity", got)
+	}
+}
@@ -18,6 +18,51 @@ type EventPoint struct {
 	T     time.Time
 	Rs    int
 	z, zc int
+	// Modifier keys held after the event.
+	Mods Mods
+
+	// Shapes that were under the pointer when the event happened, if mapped.
+	hits   []hitid
+	mapped bool
+	taken  bool // By an Activator, so it is not matched
+	hinted bool // Mods were reported by the windower, see (*Events).Hold
+}
+
+// hitid recognizes a shape in different frames: by its Identity if it has one,
+// or else by the order of construction. Shapes of Sequence elements are recognized
+// by the Sequence, the index of the element and the order inside the element.
+type hitid struct {
+	key       any
+	z, zj, z2 int
+}
+
+// hitrect is a shape of the hitmap of the previous frame.
+type hitrect struct {
+	r  geom.Rectangle
+	id hitid
+}
+
+func (s *Sorm) hitid() hitid {
+	if s.tag == 0 && s.key != nil {
+		return hitid{key: s.key}
+	}
+	if s.flags&flagSequenceMark > 0 {
+		return hitid{z: s.z, zj: s.zj, z2: s.zr - s.z2}
+	}
+	return hitid{z: s.z}
+}
+
+// in reports if the event happened inside the shape id or, if it is nil, inside r.
+func (ev *EventPoint) in(id *hitid, r geom.Rectangle) bool {
+	if !ev.mapped || id == nil {
+		return ev.Pt.In(r)
+	}
+	for _, h := range ev.hits {
+		if h == *id {
+			return true
+		}
+	}
+	return false
 }
 
 func (ev EventPoint) valuestring() string {
@@ -112,50 +157,132 @@ const (
 	RuneRight     = '\x14' // ASCII Device Control 4 — Right ...
 )
 
-type dummyerr struct{}
-
-func (*dummyerr) Error() string {
-	return ""
-}
+// Mods is a set of held modifier keys.
+type Mods uint8
 
 const (
-	anyShift = 1000000
-	anyCtrl  = 1000001
+	ModLShift Mods = 1 << iota
+	ModRShift
+	ModLCtrl
+	ModRCtrl
+	ModLAlt
+	ModRAlt
+	ModLSuper
+	ModRSuper
+
+	ModShift = ModLShift | ModRShift
+	ModCtrl  = ModLCtrl | ModRCtrl
+	ModAlt   = ModLAlt | ModRAlt
+	ModSuper = ModLSuper | ModRSuper
 )
 
+// modsides are modifiers held by either of their keys.
+var modsides = [...]Mods{ModShift, ModCtrl, ModAlt, ModSuper}
+
+// modnames maps names of modifiers in chords to Mods.
+var modnames = map[string]Mods{
+	"Shift": ModShift, "LShift": ModLShift, "RShift": ModRShift,
+	"Ctrl": ModCtrl, "LCtrl": ModLCtrl, "RCtrl": ModRCtrl,
+	"Alt": ModAlt, "LAlt": ModLAlt, "RAlt": ModRAlt,
+	"Super": ModSuper, "LSuper": ModLSuper, "RSuper": ModRSuper,
+}
+
+// modof returns the modifier of the key k, or 0 if k is not a modifier key.
+func modof(k Key) Mods {
+	switch k {
+	case KeyLShift:
+		return ModLShift
+	case KeyRShift:
+		return ModRShift
+	case KeyLCtrl:
+		return ModLCtrl
+	case KeyRCtrl:
+		return ModRCtrl
+	case KeyLAlt:
+		return ModLAlt
+	case KeyRAlt:
+		return ModRAlt
+	case KeyLSuper:
+		return ModLSuper
+	case KeyRSuper:
+		return ModRSuper
+	}
+	return 0
+}
+
+// chord reports if held modifiers are the ones wanted by a chord. A modifier named without
+// a side is held by any of its keys.
+func chord(held, want Mods) bool {
+	for _, f := range modsides {
+		h, w := held&f, want&f
+		switch {
+		case w == 0 && h != 0:
+			return false
+		case w == f && h == 0:
+			return false
+		case w != f && h&w != w:
+			return false
+		}
+	}
+	return true
+}
+
+// namechord splits a chord like Ctrl+Shift+I into modifiers and the name of the key.
+func namechord(value string) (mods Mods, key string, err error) {
+	names := strings.Split(value, "+")
+	key = names[len(names)-1]
+	if key == "" {
+		return 0, "", &nameeventError{true, "no key in chord “" + value + "”"}
+	}
+	for _, name := range names[:len(names)-1] {
+		m, ok := modnames[name]
+		if !ok {
+			return 0, "", &nameeventError{true, "no such modifier “" + name + "”"}
+		}
+		mods |= m
+	}
+	return mods, key, nil
+}
+
+// nameeventError is an error of nameevent in the type or in the value of an event.
+type nameeventError struct {
+	value bool
+	msg   string
+}
+
+func (e *nameeventError) Error() string {
+	return e.msg
+}
+
 // nameevent converts type name and value stirng into event value.
 // May be later replaced with reflection and type registry.
-func nameevent(typ string, value string) any {
+func nameevent(typ string, value string) (any, error) {
 	rechar := regexp.MustCompile("'.'")
 	renum := regexp.MustCompile("[-+]?(0|[1-9][0-9]*)")
 	intv := 0
 	var keyv Key
-	var err error = &dummyerr{}
 
 	if value == "" {
 		// Skip this whole else chain.
-		err = nil
 	} else if typ == `Drag` || typ == `Drop` {
 		if !filetype.IsMIMESupported(value) {
-			panic(`unsupported MIME type: ` + value)
+			return nil, &nameeventError{true, "unsupported MIME type “" + value + "”"}
 		}
-		err = nil
 	} else if rechar.FindString(value) == value {
 		// runev = []rune(value)[0]
-		err = nil
 	} else if renum.FindString(value) == value {
+		var err error
 		intv, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, &nameeventError{true, err.(*strconv.NumError).Err.Error()}
+		}
 	} else {
-		err = nil
 		ok := false
 		keyv, ok = keynames[value]
 		if !ok {
-			panic("no such constant “" + value + "”")
+			return nil, &nameeventError{true, "no such constant “" + value + "”"}
 		}
 	}
-	if err != nil {
-		panic("check parser for errors: " + err.Error())
-	}
 
 	v := any(nil)
 	switch typ {
@@ -168,9 +295,9 @@ func nameevent(typ string, value string) any {
 	case "Hover":
 		v = Hover{}
 	case "Press":
-		v = Press{Key: Key(keyv)}
+		v = Press{Key: keyv}
 	case "Release":
-		v = Release{Key: Key(keyv)}
+		v = Release{Key: keyv}
 	case "Scroll":
 		v = Scroll(intv)
 	case "Sweep":
@@ -180,9 +307,9 @@ func nameevent(typ string, value string) any {
 	case "Drop":
 		v = Drop{mime: strings.TrimSpace(value)}
 	default:
-		panic("unknown type: " + typ)
+		return nil, &nameeventError{false, "unknown event type “" + typ + "”"}
 	}
-	return v
+	return v, nil
 }
 
 // holdable returns true on every type that is meant to be held until
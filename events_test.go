@@ -0,0 +1,227 @@
+package contraption_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/neputevshina/contraption"
+	"github.com/neputevshina/contraption/backends/headless"
+	"github.com/neputevshina/geom"
+	"golang.org/x/exp/slices"
+)
+
+func TestMatchChord(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(
+		headless.Event{E: Press{Key: KeyLCtrl}},
+		headless.Event{E: Press{Key: KeyRShift}},
+		headless.Event{E: Press{Key: KeyI, Rune: 'I'}})
+	chord, plain := 0, 0
+	frames(wo, 10, func(wo *World) *Sorm {
+		if wo.Match(`Press(Ctrl+Shift+I)`) {
+			chord++
+		}
+		if wo.Match(`Press(Ctrl+I)`) {
+			plain++
+		}
+		return nil
+	})
+	if chord != 1 {
+		t.Errorf("Press(Ctrl+Shift+I) matched %d times, want 1", chord)
+	}
+	if plain != 0 {
+		t.Errorf("Press(Ctrl+I) matched %d times, want 0", plain)
+	}
+}
+
+func TestMatchSuperChord(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(
+		headless.Event{E: Press{Key: KeyRSuper}},
+		headless.Event{E: Press{Key: KeyI, Rune: 'I'}})
+	super, lsuper, ctrl := 0, 0, 0
+	frames(wo, 10, func(wo *World) *Sorm {
+		if wo.Match(`Press(Super+I)`) {
+			super++
+		}
+		if wo.Match(`Press(LSuper+I)`) {
+			lsuper++
+		}
+		if wo.Match(`Press(Ctrl+I)`) {
+			ctrl++
+		}
+		return nil
+	})
+	if super != 1 {
+		t.Errorf("Press(Super+I) matched %d times, want 1", super)
+	}
+	if lsuper != 0 || ctrl != 0 {
+		t.Errorf("Press(LSuper+I) and Press(Ctrl+I) matched %d and %d times, want 0", lsuper, ctrl)
+	}
+}
+
+func TestMatchQuantifiedType(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(
+		headless.Event{E: Hover{}, Pt: geom.Pt(1, 1)},
+		headless.Event{E: Click(1)})
+	n := 0
+	frames(wo, 10, func(wo *World) *Sorm {
+		// Patterns are matched from the newest event.
+		if wo.Match(`Click(1) Hover+`) {
+			n++
+		}
+		return nil
+	})
+	if n != 1 {
+		t.Errorf("Click(1) Hover+ matched %d times, want 1", n)
+	}
+}
+
+func TestPressChar(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(
+		headless.Event{E: Press{Key: KeyA}, Char: 'a'},
+		headless.Event{E: Release{Key: KeyA}},
+		headless.Event{E: Press{Key: KeyLShift}},
+		headless.Event{E: Press{Key: KeyA}, Char: 'A'})
+	var runes []rune
+	frames(wo, 12, func(wo *World) *Sorm {
+		if wo.Match(`Press(A)`) {
+			runes = append(runes, wo.Trace[0].E.(Press).Rune)
+		}
+		return nil
+	})
+	if string(runes) != "aA" {
+		t.Errorf("runes of Press(A) are %q, want %q", string(runes), "aA")
+	}
+}
+
+func TestHeadlessClock(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(
+		headless.Event{E: Click(1), Dt: 10 * time.Millisecond},
+		headless.Event{E: Unclick(1), Dt: 50 * time.Millisecond})
+	frames(wo, 10, func(wo *World) *Sorm { return nil })
+	if got, want := wo.Trace[0].T, epoch.Add(60*time.Millisecond); !got.Equal(want) {
+		t.Errorf("Unclick at %v, want %v", got, want)
+	}
+	if got, want := wo.Trace[0].T.Sub(wo.Trace[1].T), 50*time.Millisecond; got != want {
+		t.Errorf("Click was %v before Unclick, want %v", got, want)
+	}
+	if !wo.Now.Equal(wer.Clock) {
+		t.Errorf("World time is %v, want %v", wo.Now, wer.Clock)
+	}
+}
+
+func TestClickSequenceElement(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(
+		headless.Event{E: Hover{}, Pt: geom.Pt(10, 25)},
+		headless.Event{E: Click(1)})
+	var clicked []any
+	click := func(wo *World, name any) *Sorm {
+		return wo.Cond(func(m Matcher) {
+			if m.Match(`Click(1):in`) {
+				clicked = append(clicked, name)
+			}
+		})
+	}
+	q := AdhocSequence(func(i int) *Sorm {
+		// Only the topmost Cond matches the Click, so the outer compounds don't have one.
+		return wo.Compound(wo.Compound(wo.Void(50, 10), click(wo, fmt.Sprint("inner ", i))))
+	}, func() int { return 5 })
+	frames(wo, 10, func(wo *World) *Sorm {
+		return wo.Compound(wo.Vfollow(), wo.Sequence(q))
+	})
+	if want := []any{"inner 2"}; !slices.Equal(clicked, want) {
+		t.Errorf("clicked %v, want %v", clicked, want)
+	}
+}
+
+// modal takes every event until a click, which closes it.
+type modal struct{ took []any }
+
+func (m *modal) Activate(events *Events) Symbol {
+	e := events.Trace[0].E
+	m.took = append(m.took, e)
+	if _, ok := e.(Click); ok {
+		return Deactivate
+	}
+	return Ack
+}
+
+func TestActivatorCapturesPointer(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(
+		headless.Event{E: Hover{}, Pt: geom.Pt(10, 10)},
+		headless.Event{E: Click(1)},
+		headless.Event{E: Unclick(1)},
+		headless.Event{E: Click(1)})
+	a := &modal{}
+	wo.Activate(a)
+	clicks := 0
+	frames(wo, 12, func(wo *World) *Sorm {
+		return wo.Compound(wo.Void(50, 50), wo.Cond(func(m Matcher) {
+			if m.Match(`Click(1):in`) {
+				clicks++
+			}
+		}))
+	})
+	if want := []any{Hover{}, Click(1)}; !slices.Equal(a.took, want) {
+		t.Errorf("Activator took %v, want %v", a.took, want)
+	}
+	if clicks != 1 {
+		t.Errorf("Click(1) matched %d times, want only after the Activator is removed", clicks)
+	}
+}
+
+func TestFind(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(
+		headless.Event{E: Hover{}, Pt: geom.Pt(10, 10), Dt: time.Millisecond},
+		headless.Event{E: Click(1), Dt: 10 * time.Millisecond},
+		headless.Event{E: Unclick(1), Dt: 30 * time.Millisecond})
+	var f *Found
+	reversed := false
+	frames(wo, 10, func(wo *World) *Sorm {
+		// The trace is from the latest event, so is the pattern.
+		// It is found only in the frame of the fresh Unclick.
+		if found := wo.Find(`(Unclick(1)) (Scroll(1))? (Click(1) Hover)`); found != nil {
+			f = found
+		}
+		reversed = reversed || wo.Find(`Click(1) Unclick(1)`) != nil
+		return nil
+	})
+	if f == nil {
+		t.Fatal("pattern is not found")
+	}
+	unclick, click := epoch.Add(41*time.Millisecond), epoch.Add(11*time.Millisecond)
+	for k, want := range []struct {
+		begin, end int
+		events     []any
+		dur        time.Duration
+	}{
+		{0, 3, []any{Unclick(1), Click(1), Hover{}}, 40 * time.Millisecond},
+		{0, 1, []any{Unclick(1)}, 0},
+		{-1, -1, nil, 0},
+		{1, 3, []any{Click(1), Hover{}}, 10 * time.Millisecond},
+	} {
+		g := f.Groups[k]
+		var events []any
+		for _, ev := range g.Events {
+			events = append(events, ev.E)
+		}
+		if g.Begin != want.begin || g.End != want.end || !slices.Equal(events, want.events) || g.Duration != want.dur {
+			t.Errorf("group %d is [%d:%d] %v in %v, want [%d:%d] %v in %v",
+				k, g.Begin, g.End, events, g.Duration, want.begin, want.end, want.events, want.dur)
+		}
+	}
+	if g := f.Groups[0]; !g.Events[0].T.Equal(unclick) || !g.Events[1].T.Equal(click) {
+		t.Errorf("events are at %v and %v, want %v and %v", g.Events[0].T, g.Events[1].T, unclick, click)
+	}
+	if reversed {
+		t.Errorf("pattern in the reverse order is found")
+	}
+}
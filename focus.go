@@ -0,0 +1,96 @@
+package contraption
+
+import "golang.org/x/exp/slices"
+
+// Activator is an object that captures the input, like a menu, a modal dialog or an edited field.
+//
+// Active Activators are kept in a stack and receive every new event in Activate, from the top
+// of the stack down, before the event can be matched by anything else. So an Activator can capture
+// the pointer as well as the keyboard, e.g. to close a menu by a click outside of it.
+// Activate returns Silence if the Activator did not react to the event, so it is passed further,
+// Ack if it took the event, and Deactivate if it took the event and lost the focus.
+// Taken events are not matched by regular expressions and don't move the focus.
+type Activator interface {
+	Activate(events *Events) (action Symbol)
+}
+
+// ActivatorPainter is an Activator that can paint itself while it is dragged.
+type ActivatorPainter interface {
+	Activator
+	Paint(wo *World) Sorm
+}
+
+// Activate pushes a to the top of the Activator stack.
+// a receives events starting from the next one.
+func (wo *World) Activate(a Activator) {
+	wo.actives = append(wo.actives, a)
+}
+
+// Focus moves keyboard focus to the compound with the Identity key. Nil key clears the focus.
+//
+// Focus is also moved by a click on a Focusable compound, cleared by a click outside of them,
+// and traversed in layout order by Tab and Shift+Tab.
+func (wo *World) Focus(key any) {
+	wo.focus = key
+}
+
+// Focused reports if the compound with the Identity key has keyboard focus.
+func (wo *World) Focused(key any) bool {
+	return key != nil && wo.focus == key
+}
+
+// activate dispatches the new event to the Activator stack and moves focus.
+func (wo *World) activate() {
+	ev := &wo.Events.Trace[0]
+	if ev.E == nil || ev.T == wo.activated {
+		return
+	}
+	wo.activated = ev.T
+
+	for i := len(wo.actives) - 1; i >= 0; i-- {
+		switch wo.actives[i].Activate(wo.Events) {
+		case Silence:
+			continue
+		case Deactivate:
+			wo.actives = slices.Delete(wo.actives, i, i+1)
+		}
+		ev.taken = true
+		return
+	}
+
+	switch ev.E.(type) {
+	case Press:
+		switch {
+		case wo.Match(`Press(Shift+Tab)`):
+			wo.tab(-1)
+		case wo.Match(`Press(Tab)`):
+			wo.tab(1)
+		default:
+			return
+		}
+		ev.taken = true
+
+	case Click:
+		// Hits are front to back, see (*World).Develop.
+		wo.focus = nil
+		for _, h := range ev.hits {
+			if h.key != nil && slices.Contains(wo.focusables, h.key) {
+				wo.focus = h.key
+				return
+			}
+		}
+	}
+}
+
+// tab moves focus by d compounds in layout order, wrapping around.
+func (wo *World) tab(d int) {
+	n := len(wo.focusables)
+	if n == 0 {
+		return
+	}
+	i := slices.Index(wo.focusables, wo.focus)
+	if i < 0 && d < 0 {
+		i = n
+	}
+	wo.focus = wo.focusables[((i+d)%n+n)%n]
+}
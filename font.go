@@ -99,6 +99,15 @@ func (f *Font) Emtocap(em float64) float64 {
 	return fixedToFloat(f.EmtocapFixed)(em)
 }
 
+// descent returns the depth of the font below the baseline at the size em.
+func (f *Font) descent(em float64) float64 {
+	r, err := f.Parsed.Metrics(&f.buf, fixed.Int26_6(em*64), font.HintingNone)
+	if err != nil {
+		panic(err)
+	}
+	return float64(r.Descent) / 64
+}
+
 func fixedToFloat(f func(fixed.Int26_6) fixed.Int26_6) func(float64) float64 {
 	return func(x float64) float64 {
 		return float64(f(fixed.Int26_6(x*64))) / 64
@@ -304,13 +313,13 @@ func (s *stringSlice) ReadRune() (r rune, size int, err error) {
 	if s.n == len(s.r) {
 		return 0, 0, io.EOF
 	}
-	r, size = utf8.DecodeRuneInString(s.r)
+	r, size = utf8.DecodeRuneInString(s.r[s.n:])
 	s.n += size
 	return
 }
 
 func (s *stringSlice) UnreadRune() (err error) {
-	_, size := utf8.DecodeLastRuneInString(s.r)
+	_, size := utf8.DecodeLastRuneInString(s.r[:s.n])
 	s.n -= size
 	if s.n < 0 {
 		panic(`underflow`)
@@ -331,13 +340,13 @@ func (s *byteSlice) ReadRune() (r rune, size int, err error) {
 	if s.n == len(s.r) {
 		return 0, 0, io.EOF
 	}
-	r, size = utf8.DecodeRune(s.r)
+	r, size = utf8.DecodeRune(s.r[s.n:])
 	s.n += size
 	return
 }
 
 func (s *byteSlice) UnreadRune() (err error) {
-	_, size := utf8.DecodeLastRune(s.r)
+	_, size := utf8.DecodeLastRune(s.r[:s.n])
 	s.n -= size
 	if s.n < 0 {
 		panic(`underflow`)
@@ -0,0 +1,29 @@
+package contraption
+
+import (
+	"io"
+	"testing"
+)
+
+func TestStringAndBytesRunes(t *testing.T) {
+	const text = "aж€𝄞"
+	for _, rs := range []io.RuneScanner{String(text), Bytes([]byte(text))} {
+		var got []rune
+		for {
+			r, _, err := rs.ReadRune()
+			if err != nil {
+				break
+			}
+			got = append(got, r)
+			if err := rs.UnreadRune(); err != nil {
+				t.Fatal(err)
+			}
+			if r2, _, _ := rs.ReadRune(); r2 != r {
+				t.Fatalf("reread %q after UnreadRune, want %q", r2, r)
+			}
+		}
+		if string(got) != text {
+			t.Errorf("%T: read %q, want %q", rs, string(got), text)
+		}
+	}
+}
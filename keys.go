@@ -0,0 +1,202 @@
+package contraption
+
+import "strconv"
+
+// Key is a key of a keyboard, named by its place on the US layout regardless of the layout
+// in use, so hotkeys stay at their places. Text entered with a key is in the Rune of Press.
+//
+// Physical keys tell the left and right modifiers apart. Logical keys KeyShift, KeyCtrl and KeyAlt
+// are never pressed, they stand for any of their physical keys in patterns.
+//
+// Every Windower translates its native key codes to Keys.
+type Key int
+
+const (
+	KeyUnknown Key = iota
+
+	KeyA
+	KeyB
+	KeyC
+	KeyD
+	KeyE
+	KeyF
+	KeyG
+	KeyH
+	KeyI
+	KeyJ
+	KeyK
+	KeyL
+	KeyM
+	KeyN
+	KeyO
+	KeyP
+	KeyQ
+	KeyR
+	KeyS
+	KeyT
+	KeyU
+	KeyV
+	KeyW
+	KeyX
+	KeyY
+	KeyZ
+
+	Key0
+	Key1
+	Key2
+	Key3
+	Key4
+	Key5
+	Key6
+	Key7
+	Key8
+	Key9
+
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+
+	KeySpace
+	KeyComma
+	KeyPeriod
+	KeySlash
+	KeyBackslash
+	KeySemicolon
+	KeyApostrophe
+	KeyGrave
+	KeyMinus
+	KeyEqual
+	KeyLeftBracket
+	KeyRightBracket
+
+	KeyEscape
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyInsert
+	KeyDelete
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyLeft
+	KeyRight
+	KeyUp
+	KeyDown
+	KeyPrintScreen
+	KeyScrollLock
+	KeyNumLock
+	KeyCapsLock
+	KeyPause
+	KeyMenu
+
+	KeyLShift
+	KeyRShift
+	KeyLCtrl
+	KeyRCtrl
+	KeyLAlt
+	KeyRAlt
+	KeyLSuper
+	KeyRSuper
+
+	// Logical keys.
+	KeyShift
+	KeyCtrl
+	KeyAlt
+
+	keyCount
+)
+
+// keystrings are names of keys in patterns and in String.
+var keystrings = [keyCount]string{
+	KeyA: "A", KeyB: "B", KeyC: "C", KeyD: "D", KeyE: "E", KeyF: "F", KeyG: "G", KeyH: "H", KeyI: "I",
+	KeyJ: "J", KeyK: "K", KeyL: "L", KeyM: "M", KeyN: "N", KeyO: "O", KeyP: "P", KeyQ: "Q", KeyR: "R",
+	KeyS: "S", KeyT: "T", KeyU: "U", KeyV: "V", KeyW: "W", KeyX: "X", KeyY: "Y", KeyZ: "Z",
+
+	Key0: "0", Key1: "1", Key2: "2", Key3: "3", Key4: "4", Key5: "5", Key6: "6", Key7: "7", Key8: "8", Key9: "9",
+
+	KeyF1: "F1", KeyF2: "F2", KeyF3: "F3", KeyF4: "F4", KeyF5: "F5", KeyF6: "F6",
+	KeyF7: "F7", KeyF8: "F8", KeyF9: "F9", KeyF10: "F10", KeyF11: "F11", KeyF12: "F12",
+
+	KeySpace:        "Space",
+	KeyComma:        "Comma",
+	KeyPeriod:       "Period",
+	KeySlash:        "Slash",
+	KeyBackslash:    "Backslash",
+	KeySemicolon:    "Semicolon",
+	KeyApostrophe:   "Apostrophe",
+	KeyGrave:        "Grave",
+	KeyMinus:        "Minus",
+	KeyEqual:        "Equal",
+	KeyLeftBracket:  "LeftBracket",
+	KeyRightBracket: "RightBracket",
+
+	KeyEscape:      "Escape",
+	KeyEnter:       "Enter",
+	KeyTab:         "Tab",
+	KeyBackspace:   "Backspace",
+	KeyInsert:      "Insert",
+	KeyDelete:      "Delete",
+	KeyHome:        "Home",
+	KeyEnd:         "End",
+	KeyPageUp:      "PageUp",
+	KeyPageDown:    "PageDown",
+	KeyLeft:        "Left",
+	KeyRight:       "Right",
+	KeyUp:          "Up",
+	KeyDown:        "Down",
+	KeyPrintScreen: "PrintScreen",
+	KeyScrollLock:  "ScrollLock",
+	KeyNumLock:     "NumLock",
+	KeyCapsLock:    "CapsLock",
+	KeyPause:       "Pause",
+	KeyMenu:        "Menu",
+
+	KeyLShift: "LShift",
+	KeyRShift: "RShift",
+	KeyLCtrl:  "LCtrl",
+	KeyRCtrl:  "RCtrl",
+	KeyLAlt:   "LAlt",
+	KeyRAlt:   "RAlt",
+	// No names for Super/Win keys because they must be reserved for user's desktop environment.
+	// Super is still a modifier of chords, like Press(Super+I).
+
+	KeyShift: "Shift",
+	KeyCtrl:  "Ctrl",
+	KeyAlt:   "Alt",
+}
+
+// keynames maps names of keys in patterns to keys.
+var keynames = map[string]Key{
+	"Return": KeyEnter, "Context": KeyMenu, "Backtick": KeyGrave,
+	"←": KeyLeft, "→": KeyRight, "↑": KeyUp, "↓": KeyDown,
+
+	// TODO Adapt parser to support these.
+	// Hint: Token rule in regexp.peg
+	`,`: KeyComma, `.`: KeyPeriod, `/`: KeySlash, `\`: KeyBackslash, `;`: KeySemicolon, `'`: KeyApostrophe,
+	"`": KeyGrave, "-": KeyMinus, "=": KeyEqual, "[": KeyLeftBracket, "]": KeyRightBracket,
+}
+
+func init() {
+	for k, name := range keystrings {
+		if name != "" {
+			keynames[name] = Key(k)
+		}
+	}
+}
+
+func (k Key) String() string {
+	if k > KeyUnknown && k < keyCount && keystrings[k] != "" {
+		return keystrings[k]
+	}
+	return "Key(" + strconv.Itoa(int(k)) + ")"
+}
@@ -0,0 +1,425 @@
+package contraption
+
+import (
+	"io"
+	"math"
+	"unicode"
+)
+
+// Parameters of Knuth–Plass line breaking, the same as default ones in TeX.
+const (
+	kpLinePenalty     = 10
+	kpInfBad          = 10000
+	kpFlaggedDemerits = 10000 // Two lines in a row end with a replacement
+	kpFitnessDemerits = 10000 // Adjacent lines are too tight and too loose
+)
+
+type kpkind uint8
+
+const (
+	kpBox kpkind = iota
+	kpGlue
+	kpPenalty
+	kpReplacement
+)
+
+type kpitem struct {
+	kind                        kpkind
+	width, shrink, stretch, fil float64
+	cost                        float64
+	thick                       float64 // Size in the secondary axis
+	flagged                     bool    // Penalty has a replacement
+}
+
+type kpnode struct {
+	b, start int // Break and the first box of the next line
+	demerits float64
+	prev     int
+	fitness  int
+	flagged  bool
+}
+
+type kpline struct {
+	l, r   int     // Items of a line and its break
+	ratio  float64 // Adjustment of finite Glues
+	filr   float64 // Size of a unit of infinite stretch
+	length float64
+	thick  float64
+	hyphen bool // Line is ended with a replacement
+	pos    point
+}
+
+func vknuthaligner(wo *World, c *Sorm) {
+	knuthaligner(wo, c, false)
+}
+
+func hknuthaligner(wo *World, c *Sorm) {
+	knuthaligner(wo, c, true)
+}
+
+// knuthaligner is wordsaligner that chooses breaks with kpbreak.
+func knuthaligner(wo *World, c *Sorm, h bool) {
+	ax, ay := selfalignment(wo, c)
+	if h {
+		ax, ay = ay, ax
+	}
+	c.Size.X, c.Size.Y = 0, 0
+	beginaxis, endaxis := axis(h)
+	beginaxis(c)
+
+	var items []kpitem
+	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
+		if k.flags&flagDecoration > 0 {
+			return
+		}
+		beginaxis(k)
+		e := stretchof(k)
+		it := kpitem{kind: kpBox}
+		switch {
+		case k.tag == tagGlue:
+			g := k.key.(glue)
+			it = kpitem{kind: kpGlue, width: k.r, shrink: g.shrink, stretch: g.stretch}
+		case k.tag == tagPenalty:
+			it = kpitem{kind: kpPenalty, cost: k.r}
+		case k.flags&flagReplacement > 0:
+			it.kind = kpReplacement
+		case e.Y > 0:
+			it = kpitem{kind: kpGlue, fil: e.Y}
+		}
+		if it.kind == kpBox || it.kind == kpReplacement {
+			if e.Y == 0 {
+				it.width = k.Size.Y
+			}
+			if e.X == 0 {
+				it.thick = k.Size.X
+			}
+		}
+		if it.kind == kpReplacement {
+			p := &items[len(items)-1]
+			p.width = it.width
+			p.flagged = true
+		}
+		endaxis(k)
+		items = append(items, it)
+	})
+
+	lines := kpbreak(items, c.l.Y)
+	for j := range lines {
+		ln := &lines[j]
+		for i := ln.l; i < ln.r; i++ {
+			ln.thick = max(ln.thick, items[i].thick)
+		}
+		if ln.hyphen {
+			ln.thick = max(ln.thick, items[ln.r+1].thick)
+		}
+		c.Size.Y = max(c.Size.Y, ln.length)
+	}
+	x := 0.0
+	for j := range lines {
+		ln := &lines[j]
+		ln.pos = point{X: x, Y: (c.Size.Y - ln.length) * ay}
+		x += ln.thick
+	}
+	c.Size.X = x
+
+	j := 0
+	i := 0
+	y := 0.0
+	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
+		if k.flags&flagDecoration > 0 {
+			return
+		}
+		if len(lines) == 0 {
+			return
+		}
+		for j+1 < len(lines) && i >= lines[j+1].l {
+			j++
+			y = 0
+		}
+		ln := &lines[j]
+		it := items[i]
+		i++
+		stretch := false
+
+		beginaxis(k)
+		e := stretchof(k)
+		switch it.kind {
+		case kpGlue:
+			size := 0.0
+			// Glues at breaks disappear.
+			if i-1 < ln.r {
+				size = it.width + it.fil*ln.filr
+				if ln.ratio < 0 {
+					size += ln.ratio * it.shrink
+				} else {
+					size += ln.ratio * it.stretch
+				}
+			}
+			k.Size.Y = size
+			k.l.Y = size
+			stretch = it.fil > 0
+		case kpPenalty:
+			k.Size.Y = 0
+		case kpReplacement:
+			if ln.hyphen && i-1 == ln.r+1 {
+				k.flags &^= flagHidden
+			} else {
+				k.flags |= flagHidden
+			}
+		}
+		if e.X > 0 && it.kind != kpGlue && it.kind != kpPenalty {
+			k.Size.X = ln.thick
+			k.l.X = k.Size.X
+			stretch = true
+		}
+		endaxis(k)
+
+		if stretch {
+			wo.apply(c, k)
+		}
+
+		beginaxis(k)
+		// Stop laying out kids if we're clipped out of limit, unless they are scrolled into it.
+		if ln.pos.X > c.l.X && c.idx == nil {
+			k.flags |= flagBreakIteration
+		}
+		k.p.X = ln.pos.X + max(0, ln.thick-k.Size.X)*ax
+		k.p.Y = ln.pos.Y + y
+		if k.flags&flagHidden == 0 {
+			y += k.Size.Y
+		}
+		endaxis(k)
+	})
+	endaxis(c)
+
+	decorate(wo, c, h, len(lines), func(d *Sorm, j int) {
+		ln := lines[j]
+		d.Size = point{X: ln.thick, Y: ln.length}
+		d.p = ln.pos
+	})
+}
+
+// kpbreak breaks items into lines of the given width with the Knuth–Plass algorithm.
+// Lines that can't be fit are overfull.
+func kpbreak(items []kpitem, width float64) (lines []kpline) {
+	n := len(items)
+	if n == 0 {
+		return nil
+	}
+	// Prefix sums of widths, shrinks, stretches and infinite stretches.
+	sums := make([][4]float64, n+1)
+	for i, it := range items {
+		sums[i+1] = sums[i]
+		if it.kind == kpBox || it.kind == kpGlue {
+			sums[i+1][0] += it.width
+			sums[i+1][1] += it.shrink
+			sums[i+1][2] += it.stretch
+			sums[i+1][3] += it.fil
+		}
+	}
+	// after returns the first box of a line that starts after a break.
+	after := func(b int) int {
+		i := b + 1
+		for i < n && items[i].kind != kpBox {
+			i++
+		}
+		return i
+	}
+	// measure returns the length of a line and its adjustment ratio.
+	measure := func(l, b int) (length, ratio float64) {
+		length = sums[b][0] - sums[l][0]
+		if b < n && items[b].kind == kpPenalty {
+			length += items[b].width
+		}
+		// The last line and lines before forced breaks are not justified.
+		natural := b == n || after(b) == n || items[b].kind == kpPenalty && math.IsInf(items[b].cost, -1) || math.IsInf(width, 1)
+		switch {
+		case length < width:
+			if natural || sums[b][3]-sums[l][3] > 0 {
+				return length, 0
+			}
+			if y := sums[b][2] - sums[l][2]; y > 0 {
+				return length, (width - length) / y
+			}
+			return length, math.Inf(1)
+		case length > width:
+			if z := sums[b][1] - sums[l][1]; z > 0 {
+				return length, (width - length) / z
+			}
+			return length, math.Inf(-1)
+		}
+		return length, 0
+	}
+
+	nodes := []kpnode{{b: -1, start: 0, prev: -1, fitness: 1}}
+	active := []int{0}
+	for b := 0; b <= n; b++ {
+		cost := math.Inf(-1)
+		flagged := false
+		if b < n {
+			it := items[b]
+			switch {
+			case it.kind == kpGlue && b > 0 && items[b-1].kind == kpBox:
+				cost = 0
+			case it.kind == kpPenalty && it.cost < math.Inf(1):
+				cost = it.cost
+				flagged = it.flagged
+			default:
+				continue
+			}
+		}
+		forced := math.IsInf(cost, -1)
+
+		var best kpnode
+		found := false
+		last := -1
+		kept := active[:0]
+		for _, a := range active {
+			an := nodes[a]
+			if b <= an.start {
+				kept = append(kept, a)
+				continue
+			}
+			_, r := measure(an.start, b)
+			if r < -1 || forced {
+				last = a
+			} else {
+				kept = append(kept, a)
+			}
+			if r < -1 {
+				continue
+			}
+			bad := float64(kpInfBad)
+			if !math.IsInf(r, 0) {
+				bad = min(100*math.Abs(r*r*r), kpInfBad)
+			}
+			d := (kpLinePenalty + bad) * (kpLinePenalty + bad)
+			switch {
+			case cost >= 0:
+				d += cost * cost
+			case !forced:
+				d -= cost * cost
+			}
+			if flagged && an.flagged {
+				d += kpFlaggedDemerits
+			}
+			f := kpfitness(r)
+			if f-an.fitness > 1 || an.fitness-f > 1 {
+				d += kpFitnessDemerits
+			}
+			d += an.demerits
+			if !found || d < best.demerits {
+				best = kpnode{b: b, start: after(b), demerits: d, prev: a, fitness: f, flagged: flagged}
+				found = true
+			}
+		}
+		active = kept
+		if !found && len(active) == 0 && last >= 0 {
+			// Nothing fits, so make an overfull line from the latest break.
+			best = kpnode{b: b, start: after(b), demerits: nodes[last].demerits, prev: last, fitness: 0, flagged: flagged}
+			found = true
+		}
+		if found {
+			nodes = append(nodes, best)
+			active = append(active, len(nodes)-1)
+		}
+	}
+
+	// Only the nodes that end the paragraph remain active.
+	final := active[0]
+	for _, a := range active {
+		if nodes[a].demerits < nodes[final].demerits {
+			final = a
+		}
+	}
+	for k := final; k > 0; k = nodes[k].prev {
+		lines = append(lines, kpline{l: nodes[nodes[k].prev].start, r: nodes[k].b})
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	for j := range lines {
+		ln := &lines[j]
+		length, r := measure(ln.l, ln.r)
+		if math.IsInf(r, 0) {
+			r = 0
+		}
+		ln.ratio = max(r, -1)
+		if ln.ratio < 0 {
+			length += ln.ratio * (sums[ln.r][1] - sums[ln.l][1])
+		} else {
+			length += ln.ratio * (sums[ln.r][2] - sums[ln.l][2])
+		}
+		if fil := sums[ln.r][3] - sums[ln.l][3]; fil > 0 && length < width && !math.IsInf(width, 1) {
+			ln.filr = (width - length) / fil
+			length = width
+		}
+		ln.length = length
+		ln.hyphen = ln.r < n && items[ln.r].kind == kpPenalty && items[ln.r].flagged
+	}
+	return
+}
+
+// kpfitness is a fitness class of a line: tight, decent, loose or very loose.
+func kpfitness(r float64) int {
+	switch {
+	case r < -0.5:
+		return 0
+	case r <= 0.5:
+		return 1
+	case r <= 1:
+		return 2
+	}
+	return 3
+}
+
+// Text splits text into a stream of words, Glues and Penalties for Hknuth and Vknuth.
+//
+// Every word is made with word. Spaces become Glue(space, space/3, space/2), non-breaking spaces
+// also forbid a break, newlines force a break. Soft hyphens (U+00AD) are possible breaks
+// with a replacement made with hyphen, if it is not nil.
+func (wo *World) Text(rd io.RuneScanner, space float64, word func(w []rune) *Sorm, hyphen func() *Sorm) (ss []*Sorm) {
+	var w []rune
+	spaced := true
+	flush := func() {
+		if len(w) > 0 {
+			ss = append(ss, word(w))
+			w = nil
+			spaced = false
+		}
+	}
+	for {
+		r, _, err := rd.ReadRune()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			panic(err)
+		}
+		switch {
+		case r == '\n':
+			flush()
+			ss = append(ss, wo.Penalty(nil, math.Inf(-1)))
+			spaced = true
+		case r == '\u00ad':
+			flush()
+			var rep *Sorm
+			if hyphen != nil {
+				rep = hyphen()
+			}
+			ss = append(ss, wo.Penalty(rep, 50))
+		case r == '\u00a0':
+			flush()
+			ss = append(ss, wo.Penalty(nil, math.Inf(1)), wo.Glue(space, space/3, space/2))
+		case unicode.IsSpace(r):
+			flush()
+			if !spaced {
+				ss = append(ss, wo.Glue(space, space/3, space/2))
+				spaced = true
+			}
+		default:
+			w = append(w, r)
+		}
+	}
+	flush()
+	return
+}
@@ -0,0 +1,68 @@
+package contraption
+
+import (
+	"math"
+	"testing"
+)
+
+func kbox(w float64) kpitem { return kpitem{kind: kpBox, width: w} }
+
+func kglue(w, shrink, stretch float64) kpitem {
+	return kpitem{kind: kpGlue, width: w, shrink: shrink, stretch: stretch}
+}
+
+func kpenalty(w, cost float64, flagged bool) kpitem {
+	return kpitem{kind: kpPenalty, width: w, cost: cost, flagged: flagged}
+}
+
+func TestKpbreak(t *testing.T) {
+	g := kglue(5, 2, 3)
+	fil := kpitem{kind: kpGlue, fil: 1}
+	tests := []struct {
+		name   string
+		items  []kpitem
+		width  float64
+		breaks []int     // Right ends of lines
+		ratios []float64 // nil if not checked
+		length []float64 // nil if not checked
+	}{
+		{"empty", nil, 100, nil, nil, nil},
+		{"one line", []kpitem{kbox(10), g, kbox(10), g, kbox(10)}, 100, []int{5}, []float64{0}, []float64{40}},
+		{"exact", []kpitem{kbox(10), g, kbox(10), g, kbox(10)}, 25, []int{3, 5}, []float64{0, 0}, []float64{25, 10}},
+		{"stretched", []kpitem{kbox(10), g, kbox(10), g, kbox(10)}, 27, []int{3, 5}, []float64{2.0 / 3, 0}, []float64{27, 10}},
+		{"shrunk", []kpitem{kbox(10), g, kbox(10), g, kbox(10)}, 24, []int{3, 5}, []float64{-0.5, 0}, []float64{24, 10}},
+		{"infinite width", []kpitem{kbox(10), g, kbox(10), g, kbox(10)}, math.Inf(1), []int{5}, []float64{0}, []float64{40}},
+		{"forced", []kpitem{kbox(10), kpenalty(0, math.Inf(-1), false), kbox(10)}, 100, []int{1, 3}, []float64{0, 0}, []float64{10, 10}},
+		{"forbidden", []kpitem{kbox(10), kpenalty(0, math.Inf(1), false), g, kbox(10), g, kbox(10)}, 15, []int{4, 6}, nil, nil},
+		{"overfull", []kpitem{kbox(30), g, kbox(10)}, 20, []int{1, 3}, nil, []float64{30, 10}},
+		{"hyphen", []kpitem{kbox(20), kpenalty(3, 50, true), kbox(20)}, 25, []int{1, 3}, nil, []float64{23, 20}},
+		// The trailing Glue is a break, so it is discarded.
+		{"fil", []kpitem{fil, kbox(10), fil}, 100, []int{2}, []float64{0}, []float64{100}},
+		// Two short lines are better than a tight one and a loose one.
+		{"balanced", []kpitem{kbox(10), g, kbox(10), g, kbox(10), g, kbox(10)}, 30, []int{3, 7}, nil, nil},
+	}
+	for _, tt := range tests {
+		lines := kpbreak(tt.items, tt.width)
+		if len(lines) != len(tt.breaks) {
+			t.Errorf("%s: %d lines, want %d", tt.name, len(lines), len(tt.breaks))
+			continue
+		}
+		for j, ln := range lines {
+			if ln.r != tt.breaks[j] {
+				t.Errorf("%s: line %d breaks at %d, want %d", tt.name, j, ln.r, tt.breaks[j])
+			}
+			if tt.ratios != nil && math.Abs(ln.ratio-tt.ratios[j]) > 1e-9 {
+				t.Errorf("%s: line %d ratio %v, want %v", tt.name, j, ln.ratio, tt.ratios[j])
+			}
+			if tt.length != nil && math.Abs(ln.length-tt.length[j]) > 1e-9 {
+				t.Errorf("%s: line %d length %v, want %v", tt.name, j, ln.length, tt.length[j])
+			}
+		}
+		if tt.name == "hyphen" && !lines[0].hyphen {
+			t.Errorf("%s: first line is not hyphenated", tt.name)
+		}
+		if tt.name == "fil" && lines[0].filr != 90 {
+			t.Errorf("%s: fil is %v, want 90", tt.name, lines[0].filr)
+		}
+	}
+}
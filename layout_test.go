@@ -0,0 +1,500 @@
+package contraption_test
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/neputevshina/contraption"
+	"github.com/neputevshina/contraption/backends/headless"
+	"github.com/neputevshina/contraption/backends/software"
+	"github.com/neputevshina/contraption/backends/svg"
+	"github.com/neputevshina/contraption/nanovgo"
+	"github.com/neputevshina/geom"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+// probe records on-screen rectangles of compounds by names.
+type probe map[any]geom.Rectangle
+
+// box returns a compound of the size w×h which rectangle is recorded by the name.
+func (p probe) box(wo *World, name any, w, h complex128) *Sorm {
+	return wo.Compound(wo.Void(w, h), p.cond(wo, name))
+}
+
+// cond records the rectangle of a compound by the name.
+func (p probe) cond(wo *World, name any) *Sorm {
+	return wo.Cond(func(m Matcher) {
+		// Cond is also called with an empty rectangle, see (*World).Develop.
+		if r := m.Rect(); r != (geom.Rectangle{}) {
+			p[name] = r
+		}
+	})
+}
+
+// expect compares recorded rectangles with the ones in want.
+func (p probe) expect(t *testing.T, want probe) {
+	t.Helper()
+	for name, r := range want {
+		if got, ok := p[name]; !ok {
+			t.Errorf("%v is not laid out", name)
+		} else if got != r {
+			t.Errorf("%v is at %v, want %v", name, got, r)
+		}
+	}
+}
+
+func TestGrid(t *testing.T) {
+	_, wo := world(t, 100, 100)
+	p := probe{}
+	frames(wo, 2, func(wo *World) *Sorm {
+		return wo.Compound(wo.Hgrid(2), p.cond(wo, "grid"),
+			p.box(wo, "a", 10, 20), p.box(wo, "b", 30, 5),
+			p.box(wo, "c", 5, 5), p.box(wo, "d", 20, 8))
+	})
+	p.expect(t, probe{
+		"grid": geom.Rect(0, 0, 40, 28),
+		"a":    geom.Rect(0, 0, 10, 20),
+		"b":    geom.Rect(10, 0, 40, 5),
+		"c":    geom.Rect(0, 20, 5, 25),
+		"d":    geom.Rect(10, 20, 30, 28),
+	})
+
+	p = probe{}
+	frames(wo, 2, func(wo *World) *Sorm {
+		return wo.Compound(wo.Vgrid(2), p.cond(wo, "grid"),
+			p.box(wo, "a", 10, 20), p.box(wo, "b", 30, 5),
+			p.box(wo, "c", 5, 5), p.box(wo, "d", 20, 8))
+	})
+	p.expect(t, probe{
+		"grid": geom.Rect(0, 0, 50, 25),
+		"a":    geom.Rect(0, 0, 10, 20),
+		"b":    geom.Rect(0, 20, 30, 25),
+		"c":    geom.Rect(30, 0, 35, 5),
+		"d":    geom.Rect(30, 20, 50, 28),
+	})
+
+	// Stretchy kids of the first line share the rest of the limit between their tracks.
+	p = probe{}
+	frames(wo, 2, func(wo *World) *Sorm {
+		return wo.Compound(wo.Hgrid(2), p.cond(wo, "grid"),
+			wo.Void(-1, 10), p.box(wo, "b", 40, 10),
+			p.box(wo, "c", 20, 10), wo.Void(-1, -1),
+			p.box(wo, "e", 10, 10), p.box(wo, "f", 10, 10))
+	})
+	p.expect(t, probe{
+		"grid": geom.Rect(0, 0, 100, 100),
+		"b":    geom.Rect(60, 0, 100, 10),
+		"c":    geom.Rect(0, 10, 20, 20),
+		"e":    geom.Rect(0, 90, 10, 100),
+		"f":    geom.Rect(60, 90, 70, 100),
+	})
+}
+
+func TestWords(t *testing.T) {
+	_, wo := world(t, 100, 100)
+	p := probe{}
+	frames(wo, 2, func(wo *World) *Sorm {
+		return wo.Compound(wo.Hwords(nil), wo.Limit(50, 100), p.cond(wo, "words"),
+			p.box(wo, "a", 20, 10), p.box(wo, "b", 20, 5),
+			p.box(wo, "c", 20, 10), p.box(wo, "d", 40, 10))
+	})
+	p.expect(t, probe{
+		"a": geom.Rect(0, 0, 20, 10),
+		"b": geom.Rect(20, 0, 40, 5),
+		"c": geom.Rect(0, 10, 20, 20),
+		"d": geom.Rect(0, 20, 40, 30),
+	})
+}
+
+func TestWordsDecorations(t *testing.T) {
+	_, wo := world(t, 100, 100)
+	for _, seq := range []bool{false, true} {
+		p := probe{}
+		made := 0
+		frames(wo, 2, func(wo *World) *Sorm {
+			made = 0
+			perline := func() *Sorm {
+				made++
+				return wo.Compound(p.cond(wo, made))
+			}
+			words := func() *Sorm {
+				return wo.Compound(wo.Hwords(perline), wo.Limit(50, 100),
+					p.box(wo, "a", 20, 10), p.box(wo, "b", 20, 5),
+					p.box(wo, "c", 20, 10), p.box(wo, "d", 40, 10))
+			}
+			if seq {
+				return wo.Compound(wo.Vfollow(), wo.Sequence(AdhocSequence(func(int) *Sorm {
+					return words()
+				}, func() int { return 1 })))
+			}
+			return words()
+		})
+		if made != 3 {
+			t.Errorf("in a Sequence %v: %d decorations were made, want 3", seq, made)
+		}
+		p.expect(t, probe{
+			1: geom.Rect(0, 0, 40, 10),
+			2: geom.Rect(0, 10, 20, 20),
+			3: geom.Rect(0, 20, 40, 30),
+		})
+	}
+}
+
+func TestParagraphHeight(t *testing.T) {
+	f, err := NewFont(nil, goregular.TTF, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := f.Parsed.Metrics(nil, fixed.Int26_6(f.Captoem(10)*64), font.HintingNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A line is as high as the cap height and the descender.
+	h := 10 + float64(m.Descent)/64
+
+	_, wo := world(t, 100, 100)
+	par := wo.NewParagraph(goregular.TTF, Paragraph{})
+	for _, tt := range []struct {
+		name    string
+		aligner func() *Sorm
+	}{
+		{"Hgrid", func() *Sorm { return wo.Hgrid(1) }},
+		{"Hwords", func() *Sorm { return wo.Hwords(nil) }},
+		{"Vfollow", wo.Vfollow},
+	} {
+		p := probe{}
+		frames(wo, 2, func(wo *World) *Sorm {
+			return wo.Compound(tt.aligner(), wo.Limit(100, 100),
+				par(10, []rune("Paragraph")),
+				wo.Compound(wo.Vfollow(), wo.Void(100, -1), p.cond(wo, "rest")))
+		})
+		want := geom.Rect(0, h, 100, h)
+		if tt.name == "Vfollow" {
+			// The stretchy kid takes the rest after the wrapped paragraph, rounded.
+			want.Max.Y = h + math.Round(100-h)
+		}
+		if r := p["rest"]; r.Min.Y != want.Min.Y || (tt.name == "Vfollow" && r != want) {
+			t.Errorf("%s: the next kid is at %v, want %v", tt.name, r, want)
+		}
+	}
+}
+
+func TestParagraphGlyphs(t *testing.T) {
+	wer := headless.New(200, 100, 1)
+	wer.Clock = epoch
+	rer := svg.New(nil)
+	wo := New(wer, rer, Config{})
+	par := wo.NewParagraph(goregular.TTF, Paragraph{Maxlines: 1})
+	black := nanovgo.LinearGradient(0, 0, 1, 1, nanovgo.RGB(0, 0, 0), nanovgo.RGB(0, 0, 0))
+	p := probe{}
+	frames(wo, 2, func(wo *World) *Sorm {
+		// Letters have no ascenders, so glyphs don't rise above the cap height.
+		return wo.Compound(wo.Limit(80, 100),
+			wo.Compound(par(10, []rune("aa ss cc ee mm nn oo uu")), wo.Fill(black), p.cond(wo, "par")))
+	})
+	r, ok := p["par"]
+	if !ok {
+		t.Fatal("paragraph is not laid out")
+	}
+	// Every word and the ellipsis must be drawn inside the rectangle of the paragraph.
+	r = r.Inset(-1)
+	path := regexp.MustCompile(`<path d="([^"]*)"`).FindSubmatch(rer.Document)
+	if path == nil {
+		t.Fatalf("no glyphs are drawn:\n%s", rer.Document)
+	}
+	nums := regexp.MustCompile(`-?[0-9.]+`).FindAll(path[1], -1)
+	right := math.Inf(-1)
+	for i := 0; i+1 < len(nums); i += 2 {
+		x, _ := strconv.ParseFloat(string(nums[i]), 64)
+		y, _ := strconv.ParseFloat(string(nums[i+1]), 64)
+		if !geom.Pt(x, y).In(r) {
+			t.Fatalf("glyph point %vy%v is out of the paragraph at %v", x, y, r)
+		}
+		right = max(right, x)
+	}
+	if right < r.Max.X-10 {
+		t.Errorf("glyphs end at %v, want the ellipsis near %v", right, r.Max.X)
+	}
+}
+
+func TestEqualSequence(t *testing.T) {
+	_, wo := world(t, 100, 100)
+	key := wo.Eqkey()
+	p := probe{}
+	eq := func(name any, w complex128) *Sorm {
+		return wo.Compound(wo.Hequal(key), wo.Void(w, 10), p.cond(wo, name))
+	}
+	widths := []complex128{20, 40, 30}
+	frames(wo, 2, func(wo *World) *Sorm {
+		return wo.Compound(wo.Vfollow(), eq("main", 10),
+			wo.Sequence(SliceSequence2(widths, func(i int) *Sorm { return eq(i, widths[i]) })))
+	})
+	// Elements of Sequences are equalized with the rest of the tree.
+	p.expect(t, probe{
+		"main": geom.Rect(0, 0, 40, 10),
+		0:      geom.Rect(0, 10, 40, 20),
+		1:      geom.Rect(0, 20, 40, 30),
+		2:      geom.Rect(0, 30, 40, 40),
+	})
+}
+
+func TestScroll(t *testing.T) {
+	_, wo := world(t, 100, 100)
+	idx := &Index{I: 20}
+	made := map[int]bool{}
+	p := probe{}
+	q := AdhocSequence(func(i int) *Sorm {
+		made[i] = true
+		return p.box(wo, i, 50, 10)
+	}, func() int { return 1000 })
+	frames(wo, 2, func(wo *World) *Sorm {
+		return wo.Compound(wo.Vfollow(), wo.Vscroll(idx, 10), wo.Crop(), wo.Limit(100, 100), wo.Sequence(q))
+	})
+	for _, i := range []int{20, 29} {
+		if !made[i] {
+			t.Errorf("seen element %d was not made", i)
+		}
+	}
+	if made[0] || made[100] || len(made) > 30 {
+		t.Errorf("%d elements were made, want only the seen ones", len(made))
+	}
+	p.expect(t, probe{
+		20: geom.Rect(0, 0, 50, 10),
+		25: geom.Rect(0, 50, 50, 60),
+	})
+}
+
+func TestScrollGrid(t *testing.T) {
+	_, wo := world(t, 100, 100)
+	for _, tt := range []struct {
+		from, want Index
+		first      int
+		at         geom.Rectangle
+	}{
+		{Index{I: 21, O: 5}, Index{I: 20, O: 5}, 20, geom.Rect(0, -5, 50, 5)},
+		// Scrolling stops at the end.
+		{Index{I: 995}, Index{I: 980}, 980, geom.Rect(0, 0, 50, 10)},
+	} {
+		idx := tt.from
+		p := probe{}
+		q := AdhocSequence(func(i int) *Sorm {
+			return p.box(wo, i, 50, 10)
+		}, func() int { return 1000 })
+		frames(wo, 2, func(wo *World) *Sorm {
+			return wo.Compound(wo.Hgrid(2), wo.Vscroll(&idx, 10), wo.Crop(), wo.Limit(100, 100), wo.Sequence(q))
+		})
+		if idx.I != tt.want.I || idx.O != tt.want.O {
+			t.Errorf("scrolled to %d%+g, want %d%+g", idx.I, idx.O, tt.want.I, tt.want.O)
+		}
+		p.expect(t, probe{tt.first: tt.at, tt.first + 1: tt.at.Add(geom.Pt(50, 0))})
+	}
+}
+
+func TestBufferSequence(t *testing.T) {
+	_, wo := world(t, 100, 100)
+	made := map[int]int{}
+	p := probe{}
+	b := BufferSequence(AdhocSequence(func(i int) *Sorm {
+		made[i]++
+		return p.box(wo, i, 50, 10)
+	}, func() int { return 5 }), BufferLarge)
+	frames(wo, 3, func(wo *World) *Sorm {
+		return wo.Compound(wo.Vfollow(), wo.Sequence(b))
+	})
+	for i := 0; i < 5; i++ {
+		if made[i] != 1 {
+			t.Errorf("element %d was made %d times, want once", i, made[i])
+		}
+	}
+	b.Invalidate(1, 2)
+	frames(wo, 1, func(wo *World) *Sorm {
+		return wo.Compound(wo.Vfollow(), wo.Sequence(b))
+	})
+	if made[1] != 2 || made[2] != 1 {
+		t.Errorf("elements were made %v times after invalidation, want only the 1st again", made)
+	}
+}
+
+// TestPenaltyReplacement checks that replacements of Penalties take space only where lines are broken at them.
+func TestPenaltyReplacement(t *testing.T) {
+	_, wo := world(t, 100, 100)
+	for _, tt := range []struct {
+		name    string
+		aligner func() *Sorm
+		b       geom.Rectangle
+	}{
+		{"Hfollow", wo.Hfollow, geom.Rect(10, 0, 20, 10)},
+		{"Hgrid", func() *Sorm { return wo.Hgrid(3) }, geom.Rect(10, 0, 20, 10)},
+		{"Hwords", func() *Sorm { return wo.Hwords(nil) }, geom.Rect(10, 0, 20, 10)},
+		{"Hknuth", func() *Sorm { return wo.Hknuth(nil) }, geom.Rect(0, 10, 10, 20)},
+	} {
+		p := probe{}
+		frames(wo, 2, func(wo *World) *Sorm {
+			return wo.Compound(tt.aligner(), wo.Limit(50, 100),
+				p.box(wo, "a", 10, 10),
+				wo.Penalty(p.box(wo, "hyphen", 30, 10), math.Inf(-1)),
+				p.box(wo, "b", 10, 10))
+		})
+		if r := p["b"]; r != tt.b {
+			t.Errorf("%s: b is at %v, want %v", tt.name, r, tt.b)
+		}
+		if _, ok := p["hyphen"]; ok != (tt.name == "Hknuth") {
+			t.Errorf("%s: replacement is laid out: %v", tt.name, ok)
+		}
+	}
+}
+
+// scroller returns a root with 1000 kids of height 10 scrolled with idx in a viewport of 90×100
+// and a scrollbar of width 10 to the right of it.
+func scroller(idx *Index, mode Scrollmode) func(wo *World) *Sorm {
+	black := nanovgo.LinearGradient(0, 0, 1, 1, nanovgo.RGB(0, 0, 0), nanovgo.RGB(0, 0, 0))
+	return func(wo *World) *Sorm {
+		q := AdhocSequence(func(i int) *Sorm {
+			return wo.Compound(wo.Void(50, 10))
+		}, func() int { return 1000 })
+		return wo.Compound(wo.Hfollow(),
+			wo.Compound(wo.Vfollow(), wo.Vscroll(idx, 10), wo.Crop(), wo.Limit(90, 100), wo.Sequence(q)),
+			wo.Vscrollbar(10, idx, 1000, mode).Fill(black))
+	}
+}
+
+// tick runs frames of wo until the queued events are delivered, then n frames more.
+// The Clock is advanced by dt before each of those, so animations go on without events.
+// Positions of idx in pixels after each frame are returned.
+func tick(wer *headless.Windower, wo *World, n int, dt time.Duration, idx *Index, f func(wo *World) *Sorm) (pos []float64) {
+	for i := 0; i < n && wo.Next(); {
+		if wer.Pending() == 0 {
+			wer.Clock = wer.Clock.Add(dt)
+			i++
+		}
+		wo.Root(f(wo))
+		wo.Develop()
+		pos = append(pos, float64(idx.I)*10+idx.O)
+	}
+	return
+}
+
+func TestSmoothScroll(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(
+		headless.Event{E: Hover{}, Pt: geom.Pt(10, 10)},
+		headless.Event{E: Scroll(3)})
+	idx := &Index{I: 20}
+	pos := tick(wer, wo, 30, 10*time.Millisecond, idx, scroller(idx, ScrollUniform))
+	end := pos[len(pos)-1]
+	if d := math.Abs(end - 200); d != 30 {
+		t.Fatalf("scrolled by %g, want %d", d, 30)
+	}
+	// The distance left decays by the same factor every frame, until it is less than a pixel.
+	k := math.Exp(-10 / 50.)
+	for i := 1; i < len(pos); i++ {
+		a, b := math.Abs(end-pos[i-1]), math.Abs(end-pos[i])
+		if a > 1 && a < 30 && math.Abs(b-a*k) > 1e-9 {
+			t.Errorf("%g left after %g, want %g", b, a, a*k)
+		}
+	}
+}
+
+func TestKineticScroll(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(headless.Event{E: Hover{}, Pt: geom.Pt(10, 10)})
+	for i := 0; i < 5; i++ {
+		wer.Push(headless.Event{E: Scroll(-1), Dt: 20 * time.Millisecond})
+	}
+	idx := &Index{I: 500}
+	pos := tick(wer, wo, 300, 10*time.Millisecond, idx, scroller(idx, ScrollUniform))
+	// Steps of the burst scroll by 50, the rest is momentum.
+	moved := math.Abs(pos[len(pos)-1] - 5000)
+	if moved-50 < 50 {
+		t.Errorf("scrolled by %g, want at least %d of momentum after the steps", moved, 50)
+	}
+	if pos[len(pos)-1] != pos[len(pos)-50] {
+		t.Errorf("Index is still moving after 3 seconds")
+	}
+	// Momentum decays, so every frame moves less than the previous one once the steps are done.
+	for i := len(pos) - 250; i < len(pos); i++ {
+		if math.Abs(pos[i]-pos[i-1]) > math.Abs(pos[i-1]-pos[i-2])+1e-9 {
+			t.Fatalf("frame %d moved by %g after %g", i, pos[i]-pos[i-1], pos[i-1]-pos[i-2])
+		}
+	}
+}
+
+func TestScrollbarPage(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	// The thumb is at the middle of the track, so clicks below and above it scroll by a page.
+	wer.Push(
+		headless.Event{E: Hover{}, Pt: geom.Pt(95, 90)},
+		headless.Event{E: Click(1)},
+		headless.Event{E: Unclick(1)})
+	idx := &Index{I: 500}
+	f := scroller(idx, ScrollUniform)
+	if pos := tick(wer, wo, 30, 10*time.Millisecond, idx, f); pos[len(pos)-1] != 5100 {
+		t.Errorf("click below the thumb scrolled to %g, want %d", pos[len(pos)-1], 5100)
+	}
+	wer.Push(
+		headless.Event{E: Hover{}, Pt: geom.Pt(95, 10)},
+		headless.Event{E: Click(1)},
+		headless.Event{E: Unclick(1)})
+	if pos := tick(wer, wo, 30, 10*time.Millisecond, idx, f); pos[len(pos)-1] != 5000 {
+		t.Errorf("click above the thumb scrolled to %g, want %d", pos[len(pos)-1], 5000)
+	}
+}
+
+func TestScrollbarHide(t *testing.T) {
+	wer := headless.New(200, 100, 1)
+	wer.Clock = epoch
+	rer := software.New()
+	wo := New(wer, rer, Config{})
+	idx := &Index{I: 500}
+	f := scroller(idx, ScrollUniform)
+	// The thumb is at the middle of the track, it is black on the white background.
+	shown := func() bool { return rer.Image.RGBAAt(95, 50).R == 0 }
+
+	wer.Push(headless.Event{E: Hover{}, Pt: geom.Pt(150, 50)})
+	if tick(wer, wo, 5, 10*time.Millisecond, idx, f); shown() {
+		t.Errorf("scrollbar is shown before the compound is hovered")
+	}
+	wer.Push(headless.Event{E: Hover{}, Pt: geom.Pt(50, 50)})
+	if tick(wer, wo, 5, 10*time.Millisecond, idx, f); !shown() {
+		t.Errorf("scrollbar is hidden while the compound is hovered")
+	}
+	wer.Push(
+		headless.Event{E: Scroll(-1)},
+		headless.Event{E: Hover{}, Pt: geom.Pt(150, 50)})
+	if tick(wer, wo, 40, 10*time.Millisecond, idx, f); !shown() {
+		t.Errorf("scrollbar is hidden 400ms after the scroll")
+	}
+	if tick(wer, wo, 60, 10*time.Millisecond, idx, f); shown() {
+		t.Errorf("scrollbar is shown 1s after the scroll")
+	}
+}
+
+func TestScrollbarDrag(t *testing.T) {
+	for _, mode := range []Scrollmode{ScrollUniform, ScrollEstimate} {
+		// The thumb is 20 long on the track of 100, so it is moved by 80 from the start to the end.
+		for _, tt := range []struct {
+			dy   float64
+			want int
+		}{
+			{40, 495},
+			{200, 990},
+		} {
+			wer, wo := world(t, 100, 100)
+			wer.Push(
+				headless.Event{E: Hover{}, Pt: geom.Pt(95, 5)},
+				headless.Event{E: Click(1)},
+				headless.Event{E: Hover{}, Pt: geom.Pt(95, 5+tt.dy)},
+				headless.Event{E: Unclick(1)})
+			idx := &Index{}
+			tick(wer, wo, 5, 10*time.Millisecond, idx, scroller(idx, mode))
+			if idx.I != tt.want || idx.O != 0 {
+				t.Errorf("mode %d: thumb dragged by %g scrolled to %d%+g, want %d", mode, tt.dy, idx.I, idx.O, tt.want)
+			}
+		}
+	}
+}
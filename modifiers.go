@@ -25,6 +25,144 @@ func hfollowrun(wo *World, c, m *Sorm) {
 	c.aligner = alignerHfollow
 }
 
+// Hgrid lays out elements in rows of cols cells, from left to right and from top to bottom.
+//
+// Widths of columns are set by the first row, height of every row is the height
+// of its highest element. Elements with negative widths take the width of their column.
+// Negative heights are distributed between rows by the rules of negative units for shapes.
+// Halign and Valign align elements inside their cells.
+func (wo *World) Hgrid(cols int) (s *Sorm) {
+	if cols < 1 {
+		panic(`contraption: grid must have at least one column`)
+	}
+	s = wo.beginsorm()
+	s.tag = tagHgrid
+	s.r = float64(cols)
+	wo.endsorm(s)
+	return
+}
+func hgridrun(wo *World, c, m *Sorm) {
+	c.aligner = alignerHgrid
+	c.r = m.r
+}
+
+// Vgrid is Hgrid with swapped axes: it lays out elements in columns of rows cells,
+// from top to bottom and from left to right.
+func (wo *World) Vgrid(rows int) (s *Sorm) {
+	if rows < 1 {
+		panic(`contraption: grid must have at least one row`)
+	}
+	s = wo.beginsorm()
+	s.tag = tagVgrid
+	s.r = float64(rows)
+	wo.endsorm(s)
+	return
+}
+func vgridrun(wo *World, c, m *Sorm) {
+	c.aligner = alignerVgrid
+	c.r = m.r
+}
+
+// Eqkey is a key of a group of compounds which sizes must be equal.
+type Eqkey int
+
+// Eqkey returns a new key for Hequal and Vequal.
+// Keys can be made once and used in every frame.
+func (wo *World) Eqkey() Eqkey {
+	wo.eqkeys++
+	return wo.eqkeys
+}
+
+// Hequal makes the width of a compound equal to the maximum of natural widths of
+// all compounds with the same key, wherever they are in the tree.
+// Use it with Hshrink to make stretchy elements fill the equalized width.
+//
+// Elements of Sequences are equalized too, but only with the ones made in the frame,
+// e.g. the seen ones of a scrolled compound. The tree with Hequal or Vequal is laid out
+// twice per frame, first to measure compounds, so Get of every Sequence in it is called
+// twice per frame and must return equal elements.
+func (wo *World) Hequal(key Eqkey) (s *Sorm) {
+	s = wo.beginsorm()
+	s.tag = tagHequal
+	s.eq[0] = key
+	wo.eqpass = max(wo.eqpass, 1)
+	wo.endsorm(s)
+	return
+}
+func hequalrun(wo *World, c, m *Sorm) {
+	c.eq[0] = m.eq[0]
+}
+
+// Vequal is Hequal for heights.
+func (wo *World) Vequal(key Eqkey) (s *Sorm) {
+	s = wo.beginsorm()
+	s.tag = tagVequal
+	s.eq[1] = key
+	wo.eqpass = max(wo.eqpass, 1)
+	wo.endsorm(s)
+	return
+}
+func vequalrun(wo *World, c, m *Sorm) {
+	c.eq[1] = m.eq[1]
+}
+
+// Hwords lays out elements from left to right and breaks them into lines
+// from top to bottom when the width limit of a compound is exceeded.
+//
+// Sorms inserted by Between are collapsed at line breaks.
+// Elements with negative widths share the rest of their line, with negative heights
+// take the height of the line. Halign aligns lines, Valign aligns elements inside lines.
+//
+// If perline is not nil, it is called for every line and its result is laid out as
+// a background of the line, e.g. to highlight it.
+func (wo *World) Hwords(perline func() *Sorm) (s *Sorm) {
+	return wo.words(tagHwords, perline)
+}
+func hwordsrun(wo *World, c, m *Sorm) {
+	c.aligner = alignerHwords
+}
+
+// Vwords is Hwords with swapped axes: it lays out elements from top to bottom
+// in columns from left to right.
+func (wo *World) Vwords(perline func() *Sorm) (s *Sorm) {
+	return wo.words(tagVwords, perline)
+}
+func vwordsrun(wo *World, c, m *Sorm) {
+	c.aligner = alignerVwords
+}
+
+// Hknuth is Hwords that breaks lines with the Knuth–Plass algorithm: breakpoints are chosen
+// for the whole paragraph at once, and Glues of every line except the last one
+// are stretched or shrunk to fill the width limit of a compound.
+//
+// Lines can be broken only at Glues that follow a box and at Penalties.
+// Elements with negative widths act as infinitely stretchable Glues.
+// See Glue, Penalty and Text.
+func (wo *World) Hknuth(perline func() *Sorm) (s *Sorm) {
+	return wo.words(tagHknuth, perline)
+}
+func hknuthrun(wo *World, c, m *Sorm) {
+	c.aligner = alignerHknuth
+}
+
+// Vknuth is Hknuth with swapped axes.
+func (wo *World) Vknuth(perline func() *Sorm) (s *Sorm) {
+	return wo.words(tagVknuth, perline)
+}
+func vknuthrun(wo *World, c, m *Sorm) {
+	c.aligner = alignerVknuth
+}
+
+func (wo *World) words(tag tagkind, perline func() *Sorm) (s *Sorm) {
+	s = wo.beginsorm()
+	s.tag = tag
+	if perline != nil {
+		s.key = perline
+	}
+	wo.endsorm(s)
+	return
+}
+
 // Halign aligns elements horizontally.
 //
 // If amt == 0, elements are aligned to the left, if 0.5 to the middle and if 1 to the right.
@@ -45,7 +183,11 @@ func halignrun(wo *World, c, m *Sorm) {
 	})
 	c.Size.X = max(c.Size.X, x)
 	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
-		k.p.X += (x - k.Size.X) * m.Size.X
+		// Grids align elements inside cells by themselves.
+		// Scrolled compounds align kids along the main axis by themselves.
+		if !c.selfaligned() && !(c.idx != nil && c.aligner == alignerHfollow) {
+			k.p.X += (x - k.Size.X) * m.Size.X
+		}
 		k.ialign.X = m.Size.X
 	})
 }
@@ -69,7 +211,9 @@ func valignrun(wo *World, c, m *Sorm) {
 	})
 	c.Size.Y = max(c.Size.Y, y)
 	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
-		k.p.Y += (y - k.Size.Y) * m.Size.X
+		if !c.selfaligned() && !(c.idx != nil && c.aligner == alignerVfollow) {
+			k.p.Y += (y - k.Size.Y) * m.Size.X
+		}
 		k.ialign.Y = m.Size.X
 	})
 }
@@ -217,6 +361,18 @@ func sinkrun(wo *World, s *Sorm, m *Sorm) {
 	m.sinkid = 0
 }
 
+// Focusable marks a compound as a target of keyboard focus.
+// It uses compound's identity (set with Identity modifier) as a focus key, see (*World).Focused.
+func (wo *World) Focusable() (s *Sorm) {
+	s = wo.beginsorm()
+	s.tag = tagFocusable
+	wo.endsorm(s)
+	return
+}
+func focusablerun(wo *World, s *Sorm, m *Sorm) {
+	s.flags |= flagFocusable
+}
+
 // Hshrink shrinks the horizontal size of a stretchy compound to the size of the
 // children with the maximum known horizontal size.
 func (wo *World) Hshrink() (s *Sorm) {
@@ -351,29 +507,51 @@ func roundrun(wo *World, c, m *Sorm) {
 	c.flags |= flagRound
 }
 
+// Hscroll makes a compound with Hfollow or Vfollow scrollable along its main axis by the horizontal wheel,
+// every step of which scrolls it by du. The position is kept in idx.
+//
+// The compound is laid out from the kid at idx, and only the elements of Sequences
+// that fit in its limit are requested, see Index. Use it with Crop.
+// Compounds with other aligners are scrolled horizontally, but all their kids are laid out.
+// Aligning kids to the end doesn't make them a chat.
+//
+// Wheel steps are scrolled smoothly, bursts of steps and drags with the middle button
+// leave momentum. Scrolling stops at the ends of the compound.
 func (wo *World) Hscroll(idx *Index, du float64) (s *Sorm) {
-	// TODO Smooth scrolling, rate limiting/exponential decay.
+	idx.advance(wo)
 	s = wo.beginsorm()
 	s.tag = tagHscroll
 	s.idx = idx
+	s.scrolld.X = du
 	wo.endsorm(s)
 	return
 }
 func hscrollrun(wo *World, c, m *Sorm) {
-	c.idx = m.idx
+	scrollrun(wo, c, m)
+	c.flags |= flagHscroll
 }
 
+// Vscroll is Hscroll for the vertical wheel. Compounds with aligners other than Hfollow and Vfollow
+// are scrolled vertically.
 func (wo *World) Vscroll(idx *Index, du float64) (s *Sorm) {
+	idx.advance(wo)
 	s = wo.beginsorm()
 	s.tag = tagVscroll
 	s.idx = idx
+	s.scrolld.Y = du
 	wo.endsorm(s)
 	return
 }
 func vscrollrun(wo *World, c, m *Sorm) {
-	// NOTE Vscroll is a premodifier, they are sorted by tag before execution.
+	scrollrun(wo, c, m)
+}
+
+func scrollrun(wo *World, c, m *Sorm) {
+	// NOTE Scrolls are premodifiers executed after aligners are set, see (*World).prepass.
 	if c.idx != nil && c.idx != m.idx {
 		panic(`contraption: different Indexes in Hscroll and Vscroll on the same compound (id ` + sprint(c.i) + `)`)
 	}
 	c.idx = m.idx
+	c.scrolld.X = cond(m.scrolld.X != 0, m.scrolld.X, c.scrolld.X)
+	c.scrolld.Y = cond(m.scrolld.Y != 0, m.scrolld.Y, c.scrolld.Y)
 }
@@ -27,6 +27,14 @@ func (p *Paint) setPaintColor(color Color) {
 	p.Image = 0
 }
 
+// Params returns the parameters of the paint for the renderers that evaluate it by themselves.
+// Transformation maps the paint space to the user space, extent, radius and feather are the box
+// gradient parameters and inner and outer colors are the colors of the gradient.
+// For image patterns extent is the size of the image and inner color is the tint.
+func (p Paint) Params() (xform TransformMatrix, extent [2]float32, radius, feather float32, inner, outer Color) {
+	return p.xform, p.extent, p.radius, p.feather, p.innerColor, p.outerColor
+}
+
 // LinearGradient creates and returns a linear gradient. Parameters (sx,sy)-(ex,ey) specify the start and end coordinates
 // of the linear gradient, icol specifies the start color and ocol the end color.
 // The gradient is transformed by the current transform when it is passed to Context.FillPaint() or Context.StrokePaint().
@@ -0,0 +1,217 @@
+package contraption
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"unicode"
+
+	"github.com/neputevshina/contraption/nanovgo"
+	"github.com/neputevshina/geom"
+)
+
+type Textalign int
+
+const (
+	AlignLeft Textalign = iota
+	AlignCenter
+	AlignRight
+	AlignJustify
+)
+
+// Paragraph configures paragraph texts made by NewParagraph.
+type Paragraph struct {
+	// Lineheight is a distance between baselines in font sizes, 0 means 1.2.
+	Lineheight float64
+	Align      Textalign
+	// If Maxlines is not 0, lines after it are not shown and
+	// the last shown line is truncated with an ellipsis.
+	Maxlines int
+}
+
+type paragraph struct {
+	Paragraph
+	font  *Font
+	runes []rune
+	cap   float64
+
+	// Layout
+	capw, lh float64
+	words    []paraword
+	ellipsis paraword
+}
+
+type paraword struct {
+	l, r int // Runes
+	x    float64
+	line int
+}
+
+var ellipsis = []rune{'…'}
+
+// NewParagraph returns a constructor of multiline texts, where size is the height of a capital letter.
+//
+// Paragraph is wrapped at the width limit of its compound. Spaces between words are collapsed,
+// newlines always break a line. Width of a paragraph is the width of its longest line,
+// or the limit if it is not aligned to the left. Height reaches the descender of the last line.
+func (wo *World) NewParagraph(font []byte, par Paragraph) func(size float64, str []rune) *Sorm {
+	name := strconv.FormatUint(rand.Uint64(), 36)
+	f, err := NewFont(wo.Vgo, font, name)
+	if err != nil {
+		panic(err)
+	}
+	if par.Lineheight == 0 {
+		par.Lineheight = 1.2
+	}
+	return func(size float64, str []rune) *Sorm {
+		s := wo.beginsorm()
+		s.tag = tagParagraph
+		s.Size.Y = size
+		s.vecfont = f
+		s.key = &paragraph{Paragraph: par, font: f, runes: str, cap: size}
+		wo.endsorm(s)
+		return s
+	}
+}
+
+// wrap lays the paragraph k out at its limit.
+func (wo *World) wrap(k *Sorm) {
+	p := k.key.(*paragraph)
+	p.capw = k.m.ApplyPt(geom.Pt(0, p.cap)).Y
+	k.Size.X, k.Size.Y = p.wrap(k.l.X)
+}
+
+func (p *paragraph) wrap(width float64) (w, h float64) {
+	type line struct {
+		l, r  int // Words
+		width float64
+		hard  bool // Ended with a newline
+	}
+	em := p.font.Captoem(p.capw)
+	space := p.font.Advance(' ') * em
+	p.lh = p.Lineheight * em
+	p.words = p.words[:0]
+	p.ellipsis = paraword{line: -1}
+
+	var lines []line
+	cur := line{}
+	newline := func(hard bool) {
+		cur.hard = hard
+		lines = append(lines, cur)
+		cur = line{l: len(p.words), r: len(p.words)}
+	}
+	n := len(p.runes)
+	for i := 0; i < n; {
+		r := p.runes[i]
+		if r == '\n' {
+			newline(true)
+			i++
+			continue
+		}
+		if unicode.IsSpace(r) {
+			i++
+			continue
+		}
+		j := i
+		for j < n && !unicode.IsSpace(p.runes[j]) {
+			j++
+		}
+		ww := p.font.Measure(p.capw, p.runes[i:j])
+		gap := 0.0
+		if cur.r > cur.l {
+			gap = space
+		}
+		if cur.r > cur.l && cur.width+gap+ww > width {
+			newline(false)
+			gap = 0
+		}
+		p.words = append(p.words, paraword{l: i, r: j, x: cur.width + gap, line: len(lines)})
+		cur.width += gap + ww
+		cur.r++
+		i = j
+	}
+	newline(true)
+
+	if p.Maxlines > 0 && len(lines) > p.Maxlines {
+		lines = lines[:p.Maxlines]
+		last := &lines[len(lines)-1]
+		p.words = p.words[:last.r]
+		ew := p.font.Measure(p.capw, ellipsis)
+		// Drop runes from the end until the ellipsis fits.
+		for last.r > last.l {
+			wd := &p.words[last.r-1]
+			end := wd.x + p.font.Measure(p.capw, p.runes[wd.l:wd.r])
+			if end+ew <= width {
+				break
+			}
+			wd.r--
+			if wd.r == wd.l {
+				last.r--
+				p.words = p.words[:last.r]
+			}
+		}
+		x := 0.0
+		if last.r > last.l {
+			wd := p.words[last.r-1]
+			x = wd.x + p.font.Measure(p.capw, p.runes[wd.l:wd.r])
+		}
+		p.ellipsis = paraword{x: x, line: len(lines) - 1}
+		last.width = x + ew
+		last.hard = true
+	}
+
+	for _, ln := range lines {
+		w = max(w, ln.width)
+	}
+	if p.Align != AlignLeft && !math.IsInf(width, 1) {
+		w = max(w, width)
+	}
+	for j, ln := range lines {
+		off, extra := 0.0, 0.0
+		switch p.Align {
+		case AlignCenter:
+			off = (w - ln.width) / 2
+		case AlignRight:
+			off = w - ln.width
+		case AlignJustify:
+			if !ln.hard && ln.r-ln.l > 1 {
+				extra = (w - ln.width) / float64(ln.r-ln.l-1)
+			}
+		}
+		for i := ln.l; i < ln.r; i++ {
+			p.words[i].x += off + extra*float64(i-ln.l)
+		}
+		if p.ellipsis.line == j {
+			p.ellipsis.x += off
+		}
+	}
+	// The last line is as deep as the descender of the font.
+	h = p.capw + float64(len(lines)-1)*p.lh + p.font.descent(em)
+	return
+}
+
+func paragraphrun(wo *World, s *Sorm) {
+	if s.fill == (nanovgo.Paint{}) {
+		return
+	}
+	p := s.key.(*paragraph)
+	vgo := wo.Vgo
+	vgo.BeginPath()
+	vgo.SetFillPaint(s.fill)
+	at := func(w paraword) {
+		// Top left is at the cap height above the baseline.
+		// SetTransform multiplies onto the current transform, so it is reset for every word.
+		vgo.ResetTransform()
+		vgo.SetTransform(nanovgo.TranslateMatrix(float32(s.p.X+w.x), float32(s.p.Y+p.capw+float64(w.line)*p.lh)))
+	}
+	for _, w := range p.words {
+		at(w)
+		makealinerd(vgo, p.font, p.capw, Runes(p.runes[w.l:w.r]), false, true)
+	}
+	if p.ellipsis.line >= 0 {
+		at(p.ellipsis)
+		makealinerd(vgo, p.font, p.capw, Runes(ellipsis), false, true)
+	}
+	vgo.Fill()
+	vgo.ResetTransform()
+}
@@ -2,12 +2,11 @@ package contraption
 
 import (
 	"fmt"
-	"log"
 	"reflect"
 	"strings"
 	"time"
+	"unicode"
 
-	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/neputevshina/geom"
 )
 
@@ -28,10 +27,13 @@ type rinst struct {
 
 	e          any
 	typeonly   bool
+	mods       Mods // Of a chord
+	chord      bool
 	where      pegRule
 	begin, end bool
 
 	x, y int
+	slot int // Of rsave
 }
 
 func (i *rinst) String() string {
@@ -57,27 +59,121 @@ func (i *rinst) String() string {
 		rmatch:   "rmatch",
 		rsplit:   "rsplit",
 		rnotchar: "rnotchar",
+		rsave:    "rsave",
 	}[i.opcode], " ", i.x, " ", i.y, in, begin, end, " ", reflect.TypeOf(i.e).Name(), " (", i.e, ")}")
 }
 
-func rcompile(pattern string) ([]rinst, error) {
-	var err error
-	peg := &rpeg{}
-	err = peg.Init(func(r *rpeg) error { r.Buffer = pattern; return nil })
+// RegexpError is an error in an event regexp.
+type RegexpError struct {
+	Pattern string
+	Column  int // In runes, from 1
+	Msg     string
+}
+
+func (e *RegexpError) Error() string {
+	return fmt.Sprintf("contraption: regexp “%s”: column %d: %s", e.Pattern, e.Column, e.Msg)
+}
+
+// Program is a compiled event regexp.
+type Program struct {
+	pattern Regexp
+	insts   []rinst
+}
+
+// CompileRegexp parses an event regexp and checks names and values of its events.
+func CompileRegexp(pattern Regexp) (*Program, error) {
+	v, err := rcompile(string(pattern))
+	if err != nil {
+		return nil, err
+	}
+	return &Program{pattern: pattern, insts: v}, nil
+}
+
+// MustCompileRegexp is CompileRegexp that panics on an error.
+func MustCompileRegexp(pattern Regexp) *Program {
+	p, err := CompileRegexp(pattern)
 	if err != nil {
-		log.Panicln(err)
+		panic(err)
 	}
-	err = peg.Parse()
+	return p
+}
+
+func (p *Program) String() string {
+	return string(p.pattern)
+}
+
+// Match is (Matcher).Match with a compiled pattern.
+func (p *Program) Match(m Matcher) bool {
+	ok, _ := m.u.exec(p.insts, m, false)
+	return ok
+}
+
+// Find is (Matcher).Find with a compiled pattern.
+func (p *Program) Find(m Matcher) *Found {
+	ok, caps := m.u.exec(p.insts, m, true)
+	if !ok {
+		return nil
+	}
+	return m.u.found(caps)
+}
+
+func rcompile(pattern string) (v []rinst, err error) {
+	peg := &rpeg{}
+	err = peg.Init(func(r *rpeg) error { r.Buffer = pattern; return nil })
 	if err != nil {
 		return nil, err
 	}
+	fail := func(at uint32, msg string) *RegexpError {
+		return &RegexpError{Pattern: pattern, Column: int(at) + 1, Msg: msg}
+	}
+	n := uint32(len(peg.buffer) - 1) // Without the end symbol
+	unexpected := func(at uint32) *RegexpError {
+		for at < n && unicode.IsSpace(peg.buffer[at]) {
+			at++
+		}
+		if at >= n {
+			return fail(at, "unexpected end of pattern")
+		}
+		return fail(at, "unexpected “"+string(peg.buffer[at])+"”")
+	}
+	if perr, ok := peg.Parse().(*parseError); ok {
+		return nil, unexpected(perr.max.end)
+	}
+	root := peg.AST()
+	if root == nil {
+		return nil, unexpected(0)
+	}
 	//rprint(0, peg.AST(), pattern)
 	//peg.AST().PrettyPrint(os.Stdout, pattern)
-	v := rvisit(peg.AST().up, nil, peg.buffer)
+
+	// Errors of names and values are raised from the depth of rvisit.
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*RegexpError)
+			if !ok {
+				panic(r)
+			}
+			e.Pattern = pattern
+			v, err = nil, e
+		}
+	}()
+	groups := 0
+	v = rvisit(root.up, nil, peg.buffer, &groups)
 	v = append(v, rinst{opcode: rmatch})
 	return v, nil
 }
 
+// rgroups returns the count of groups in the program, including the whole match.
+func rgroups(program []rinst) int {
+	n := 1
+	for _, i := range program {
+		if i.opcode == rsave {
+			n = max(n, i.slot/2+1)
+		}
+	}
+	return n
+}
+
 type rlist struct {
 	rinst
 	next *rinst
@@ -94,41 +190,35 @@ func rprint(tab int, n *node32, pattern string) {
 	rprint(tab, n.next, pattern)
 }
 
-func rvisit(n, p *node32, pattern []rune) []rinst {
+// rvisit compiles the node n. Groups are numbered by their opening parentheses, groups counts them.
+func rvisit(n, p *node32, pattern []rune, groups *int) []rinst {
 	if n == nil {
 		return nil
 	}
-	adjust := func(r []rinst, n int) {
-		for i := range r {
-			r[i].x += n
-			r[i].y += n
-		}
-	}
-
 	switch n.token32.pegRule {
 	case ruleAlter:
-		left := rvisit(n.up, n, pattern)
-		right := rvisit(n.up.next, n, pattern)
+		left := rvisit(n.up, n, pattern, groups)
+		right := rvisit(n.up.next, n, pattern, groups)
 		v := []rinst{
 			{opcode: rsplit,
 				x: 1,
 				y: 2 + len(left), // one for this, and one for next rjmp
 			},
 		}
-		adjust(left, 1) // for previous rsplit
+		radjust(left, 1) // for previous rsplit
 		v = append(v, left...)
 		v = append(v, rinst{
 			opcode: rjmp,
 			x:      1 + len(left) + 1 + len(right),
 		})
-		adjust(right, 2+len(left)) // rsplit+left+rjmp
+		radjust(right, 2+len(left)) // rsplit+left+rjmp
 		v = append(v, right...)
 		return v
 
 	case ruleCat:
-		left := rvisit(n.up, n, pattern)
-		right := rvisit(n.up.next, n, pattern)
-		adjust(right, len(left))
+		left := rvisit(n.up, n, pattern, groups)
+		right := rvisit(n.up.next, n, pattern, groups)
+		radjust(right, len(left))
 		return append(left, right...)
 
 	case rulePoint:
@@ -136,6 +226,8 @@ func rvisit(n, p *node32, pattern []rune) []rinst {
 		left := n.up.up // Concrete -> {} here
 		typ := ""
 		val := ""
+		chord := false
+		var typat, valat uint32
 		for left != nil {
 			switch left.pegRule {
 			case ruleRect:
@@ -149,8 +241,11 @@ func rvisit(n, p *node32, pattern []rune) []rinst {
 				}
 			case ruleType:
 				typ = string(pattern[left.begin:left.end])
+				typat = left.begin
 			case ruleValue:
 				val = string(pattern[left.begin:left.end])
+				valat = left.begin
+				chord = left.up.pegRule == ruleChord
 			}
 			left = left.next
 		}
@@ -160,79 +255,150 @@ func rvisit(n, p *node32, pattern []rune) []rinst {
 			pt.opcode = rchar
 			if typ[0] == '!' { // TODO make an operator
 				typ = typ[1:]
+				typat++
 				pt.opcode = rnotchar
 			}
-			pt.e = nameevent(typ, val)
+			key := val
+			var err error
+			if chord {
+				if typ != "Press" && typ != "Release" {
+					err = &nameeventError{true, "chord “" + val + "” is only for Press and Release"}
+				} else {
+					pt.chord = true
+					pt.mods, key, err = namechord(val)
+				}
+			}
+			e := any(nil)
+			if err == nil {
+				e, err = nameevent(typ, key)
+			}
+			if err != nil {
+				at := typat
+				if err.(*nameeventError).value {
+					at = valat
+				}
+				panic(&RegexpError{Column: int(at) + 1, Msg: err.Error()})
+			}
+			pt.e = e
 			if val == "" {
 				pt.typeonly = true
 			}
 		}
-		left = n.up // Concrete
-		if left.next != nil {
-			switch left.next.pegRule {
-			case ruleMaybe:
-				v := []rinst{
-					{opcode: rsplit,
-						x: 1,
-						y: 2,
-					},
-					pt,
-				}
-				return v
-
-			case ruleAny:
-				// TODO groups
-				v := []rinst{
-					{opcode: rsplit,
-						x: 1,
-						y: 3},
-					pt,
-					{opcode: rjmp,
-						x: 0},
-				}
-				return v
-
-			case ruleLazyAny:
-				v := []rinst{
-					{opcode: rsplit,
-						x: 3,
-						y: 1},
-					pt,
-					{opcode: rjmp,
-						x: 0},
-				}
-				return v
+		return rloop([]rinst{pt}, n.up.next)
+
+	case ruleGroup:
+		*groups++
+		k := *groups
+		v := []rinst{{opcode: rsave, slot: 2 * k}}
+		v = append(v, radjust(rvisit(n.up, n, pattern, groups), 1)...)
+		v = append(v, rinst{opcode: rsave, slot: 2*k + 1})
+		return rloop(v, n.up.next)
+	}
+	return []rinst{}
+}
 
-			case ruleSeveral:
-				panic("unimplemented, currently not needed")
-			default:
-				panic("check parser for errors")
+// radjust moves jumps of r by n instructions.
+func radjust(r []rinst, n int) []rinst {
+	for i := range r {
+		r[i].x += n
+		r[i].y += n
+	}
+	return r
+}
 
-			}
-		}
-		return []rinst{pt}
+// rloop repeats the program v as the quantifier q says. q is nil if there is no quantifier.
+func rloop(v []rinst, q *node32) []rinst {
+	if q == nil {
+		return v
 	}
-	return []rinst{}
+	l := len(v)
+	// Greedy quantifiers prefer the body, lazy ones prefer what follows.
+	switch q.pegRule {
+	case ruleMaybe:
+		return append([]rinst{{opcode: rsplit, x: 1, y: l + 1}}, radjust(v, 1)...)
+	case ruleLazyMaybe:
+		return append([]rinst{{opcode: rsplit, x: l + 1, y: 1}}, radjust(v, 1)...)
+	case ruleAny:
+		v = append([]rinst{{opcode: rsplit, x: 1, y: l + 2}}, radjust(v, 1)...)
+		return append(v, rinst{opcode: rjmp, x: 0})
+	case ruleLazyAny:
+		v = append([]rinst{{opcode: rsplit, x: l + 2, y: 1}}, radjust(v, 1)...)
+		return append(v, rinst{opcode: rjmp, x: 0})
+	case ruleSeveral:
+		return append(v, rinst{opcode: rsplit, x: 0, y: l + 1})
+	case ruleLazySeveral:
+		return append(v, rinst{opcode: rsplit, x: l + 1, y: 0})
+	}
+	panic("check parser for errors")
 }
 
 // rinterp is the threaded regular expression bytecode vm taken from https://swtch.com/~rsc/regexp/regexp2.html#thompsonvm.
-func rinterp(program []rinst, trace []EventPoint, rect geom.Rectangle, dur time.Duration, deadline time.Time, z int, alwaysin bool) (bool, EventTraceLast) {
+//
+// If ncap > 0, threads save positions of groups like in https://swtch.com/~rsc/regexp/regexp2.html#pike
+// and caps has ncap positions in the trace, where the pair 2k, 2k+1 is the beginning and the end
+// of the group k and the group 0 is the whole match. Not matched groups are -1.
+func rinterp(program []rinst, trace []EventPoint, m Matcher, ncap int) (ok bool, last EventTraceLast, caps []int) {
+	z, alwaysin, dur, deadline := m.z, m.alwaysin, m.dur, m.deadline
+	// Shapes that have just appeared are matched by their rectangle, as they weren't in the hitmap.
+	var id *hitid
+	if m.hit != (hitid{}) && m.u.hitids[m.hit] {
+		id = &m.hit
+	}
 	// println(`rinterp`)
-	type rthread = int
+	type rthread struct {
+		pc   int
+		caps []int
+	}
 	cs := make([]rthread, 0, len(program))
 	ns := make([]rthread, 0, len(program))
 	var left, right time.Time
 	box := geom.Rectangle{}
+	// A thread is added to a list once per event, so loops of empty groups like (.*)* end.
+	// Jumps, splits and saves are followed right away, depth first, so lists are ordered by priority
+	// and greedy quantifiers prefer longer matches, while lazy ones prefer shorter.
+	con := make([]int, len(program))
+	non := make([]int, len(program))
+	var add func(list *[]rthread, on []int, j int, t rthread)
+	add = func(list *[]rthread, on []int, j int, t rthread) {
+		if on[t.pc] == j+1 {
+			return
+		}
+		on[t.pc] = j + 1
+		v := &program[t.pc]
+		switch v.opcode {
+		case rjmp:
+			add(list, on, j, rthread{v.x, t.caps})
+		case rsplit:
+			add(list, on, j, rthread{v.x, t.caps})
+			add(list, on, j, rthread{v.y, t.caps})
+		case rsave:
+			if t.caps != nil && v.slot < len(t.caps) {
+				t.caps = append([]int(nil), t.caps...)
+				t.caps[v.slot] = j
+			}
+			add(list, on, j, rthread{t.pc + 1, t.caps})
+		default:
+			*list = append(*list, t)
+		}
+	}
 
-	cs = append(cs, 0)
-	var prev EventPoint
+	start := rthread{}
+	if ncap > 0 {
+		start.caps = make([]int, ncap)
+		for i := range start.caps {
+			start.caps[i] = -1
+		}
+	}
+	add(&cs, con, 0, start)
 	choked := false
+	reached := false // The end of the pattern was reached, but maybe not in time
+	marked := false
 	for j, sv := range trace {
 		sv := sv
 		for i := 0; i < len(cs); i++ {
 			// j := j
-			pc := cs[i]
-			v := &program[pc]
+			t := cs[i]
+			v := &program[t.pc]
 
 			joker := func() {
 				defer func() {
@@ -241,16 +407,19 @@ func rinterp(program []rinst, trace []EventPoint, rect geom.Rectangle, dur time.
 					}
 					trace[j].zc = z
 				}()
+				if sv.taken {
+					return
+				}
 				// This comparison is dependent on the Cond evaluation order in (*World).Develop.
 				where := v.where
 				if alwaysin && where == 0 {
 					where = ruleIn
 				}
 				if where != ruleAnywhere {
-					if where == ruleIn && !sv.Pt.In(rect) {
+					if where == ruleIn && !sv.in(id, m.rect) {
 						return
 					}
-					if where == ruleOut && sv.Pt.In(rect) {
+					if where == ruleOut && sv.in(id, m.rect) {
 						return
 					}
 				}
@@ -272,8 +441,7 @@ func rinterp(program []rinst, trace []EventPoint, rect geom.Rectangle, dur time.
 				box.Min.Y = min(box.Min.Y, sv.Pt.Y)
 				box.Max.X = min(box.Max.X, sv.Pt.X)
 				box.Max.Y = min(box.Max.Y, sv.Pt.Y)
-				ns = append(ns, cs[i]+1)
-				prev = sv
+				add(&ns, non, j+1, rthread{t.pc + 1, t.caps})
 			}
 
 			switch v.opcode {
@@ -293,8 +461,9 @@ func rinterp(program []rinst, trace []EventPoint, rect geom.Rectangle, dur time.
 
 			case rmatch:
 				// If right (starting time) is not specified end of the match will be on its end, duh.
-				if right == (time.Time{}) {
-					right = prev.T
+				right := right
+				if right == (time.Time{}) && j > 0 {
+					right = trace[j-1].T
 				}
 				e := EventTraceLast{
 					StartedAt:  right,
@@ -302,34 +471,48 @@ func rinterp(program []rinst, trace []EventPoint, rect geom.Rectangle, dur time.
 					Box:        box,
 					FirstTouch: sv.Pt,
 				}
-				// Set z candidates to z because we matched.
-				defer func() {
-					for j := range trace {
-						if trace[j].zc == z {
-							trace[j].z = trace[j].zc
+				if !marked {
+					marked = true
+					// Set z candidates to z because we matched.
+					defer func() {
+						for j := range trace {
+							if trace[j].zc == z {
+								trace[j].z = trace[j].zc
+							}
 						}
-					}
-				}()
+					}()
+				}
 				// Deadline is one who is changing there.
-				return left.Sub(right) <= dur && deadline.Before(left), e
-
-			case rjmp:
-				cs = append(cs, v.x)
-
-			case rsplit:
-				cs = append(cs, v.x)
-				cs = append(cs, v.y)
+				if left.Sub(right) > dur || !deadline.Before(left) {
+					if !reached {
+						reached, last = true, e
+					}
+					continue
+				}
+				if t.caps != nil {
+					t.caps[0], t.caps[1] = 0, j
+				}
+				// Threads of lower priority are cut, the ones of higher priority may match later.
+				ok, last, caps = true, e, t.caps
+				cs = cs[:i]
 			}
 		}
 		cs, ns = ns, cs
+		con, non = non, con
 		ns = ns[:0]
+		if len(cs) == 0 {
+			break
+		}
 	}
 
-	return false, EventTraceLast{Choked: choked}
+	if ok || reached {
+		return
+	}
+	return false, EventTraceLast{Choked: choked}, nil
 }
 
 type keyer interface {
-	key() glfw.Key
+	key() Key
 }
 
 // Moved to nanovgo+glfw.go
@@ -353,57 +536,3 @@ type keyer interface {
 // 	}
 // 	return p.E == inst.e
 // }
-
-/*
-// pike's version of above, supports submatches https://swtch.com/~rsc/regexp/regexp2.html#pike
-func rinterp(program []rinst, trace []EventPoint, rect image.Rectangle, dur time.Duration) (match bool, submatches [10][]EventPoint) {
-	type rthread struct {
-		pc    *rinst
-		saved [10][]EventPoint
-	}
-
-	clist := make([]rthread, 0, len(program))
-	nlist := make([]rthread, 0, len(program))
-	var begin, end time.Time
-	in := true
-
-	clist = append(clist, rthread{&program[0], submatches})
-	for si, sv := range trace {
-		for i, t := range clist {
-			switch t.pc.opcode {
-			case rchar:
-				if t.pc.begin {
-					begin = sv.T
-				} else if t.pc.end {
-					end = sv.T
-				}
-				if t.pc.in {
-					in = in && sv.Pt.In(rect)
-				}
-				if !requals(sv, t.pc) {
-					break
-				}
-				clist = append(clist, rthread{&program[i+1], t.saved})
-
-			case rmatch:
-				submatches = t.saved
-				return in && end.Sub(begin) <= dur, submatches
-
-			case rjmp:
-				clist = append(clist, rthread{t.pc.x, t.saved})
-
-			case rsplit:
-				clist = append(clist, rthread{t.pc.x, t.saved})
-				clist = append(clist, rthread{t.pc.y, t.saved})
-
-			case rsave:
-				t.saved[t.pc.i] = trace[si:]
-				clist = append(clist, rthread{t.pc.x, t.saved})
-			}
-		}
-		clist, nlist = nlist, clist
-		nlist = nlist[:0]
-	}
-	return false, submatches
-}
-*/
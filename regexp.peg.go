@@ -21,6 +21,7 @@ const (
 	ruleBody
 	ruleAlter
 	ruleCat
+	ruleGroup
 	rulePoint
 	ruleConcrete
 	ruleAny
@@ -42,6 +43,7 @@ const (
 	ruleNumber
 	ruleChar
 	ruleSP
+	ruleChord
 )
 
 var rul3s = [...]string{
@@ -49,6 +51,7 @@ var rul3s = [...]string{
 	"Body",
 	"Alter",
 	"Cat",
+	"Group",
 	"Point",
 	"Concrete",
 	"Any",
@@ -70,6 +73,7 @@ var rul3s = [...]string{
 	"Number",
 	"Char",
 	"SP",
+	"Chord",
 }
 
 type token32 struct {
@@ -191,7 +195,7 @@ type rpeg struct {
 
 	Buffer string
 	buffer []rune
-	rules  [25]func() bool
+	rules  [27]func() bool
 	parse  func(rule ...int) error
 	reset  func()
 	Pretty bool
@@ -371,7 +375,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 
 	_rules = [...]func() bool{
 		nil,
-		/* 0 Body <- <(Alter / Cat)> */
+		/* 0 Body <- <((Alter / Cat) SP !.)> */
 		func() bool {
 			position0, tokenIndex0 := position, tokenIndex
 			{
@@ -389,6 +393,18 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 					}
 				}
 			l2:
+				if !_rules[ruleSP]() {
+					goto l0
+				}
+				{
+					position199, tokenIndex199 := position, tokenIndex
+					if !matchDot() {
+						goto l199
+					}
+					goto l0
+				l199:
+					position, tokenIndex = position199, tokenIndex199
+				}
 				add(ruleBody, position1)
 			}
 			return true
@@ -396,275 +412,355 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position0, tokenIndex0
 			return false
 		},
-		/* 1 Alter <- <((Cat (' ' / '\t' / '\n')* '|' (' ' / '\t' / '\n')* Cat) / (Cat (' ' / '\t' / '\n')* '|' (' ' / '\t' / '\n')* Alter))> */
+		/* 1 Alter <- <(Cat (' ' / '\t' / '\n')* '|' (' ' / '\t' / '\n')* (Alter / Cat))> */
 		func() bool {
-			position4, tokenIndex4 := position, tokenIndex
+			position142, tokenIndex142 := position, tokenIndex
 			{
-				position5 := position
+				position143 := position
+				if !_rules[ruleCat]() {
+					goto l142
+				}
+			l144:
 				{
-					position6, tokenIndex6 := position, tokenIndex
-					if !_rules[ruleCat]() {
-						goto l7
-					}
-				l8:
+					position145, tokenIndex145 := position, tokenIndex
 					{
-						position9, tokenIndex9 := position, tokenIndex
-						{
-							position10, tokenIndex10 := position, tokenIndex
-							if buffer[position] != rune(' ') {
-								goto l11
-							}
-							position++
-							goto l10
-						l11:
-							position, tokenIndex = position10, tokenIndex10
-							if buffer[position] != rune('\t') {
-								goto l12
-							}
-							position++
-							goto l10
-						l12:
-							position, tokenIndex = position10, tokenIndex10
-							if buffer[position] != rune('\n') {
-								goto l9
-							}
-							position++
+						position146, tokenIndex146 := position, tokenIndex
+						if buffer[position] != rune(' ') {
+							goto l147
 						}
-					l10:
-						goto l8
-					l9:
-						position, tokenIndex = position9, tokenIndex9
-					}
-					if buffer[position] != rune('|') {
-						goto l7
-					}
-					position++
-				l13:
-					{
-						position14, tokenIndex14 := position, tokenIndex
-						{
-							position15, tokenIndex15 := position, tokenIndex
-							if buffer[position] != rune(' ') {
-								goto l16
-							}
-							position++
-							goto l15
-						l16:
-							position, tokenIndex = position15, tokenIndex15
-							if buffer[position] != rune('\t') {
-								goto l17
-							}
-							position++
-							goto l15
-						l17:
-							position, tokenIndex = position15, tokenIndex15
-							if buffer[position] != rune('\n') {
-								goto l14
-							}
-							position++
+						position++
+						goto l146
+					l147:
+						position, tokenIndex = position146, tokenIndex146
+						if buffer[position] != rune('\t') {
+							goto l148
 						}
-					l15:
-						goto l13
-					l14:
-						position, tokenIndex = position14, tokenIndex14
-					}
-					if !_rules[ruleCat]() {
-						goto l7
-					}
-					goto l6
-				l7:
-					position, tokenIndex = position6, tokenIndex6
-					if !_rules[ruleCat]() {
-						goto l4
-					}
-				l18:
-					{
-						position19, tokenIndex19 := position, tokenIndex
-						{
-							position20, tokenIndex20 := position, tokenIndex
-							if buffer[position] != rune(' ') {
-								goto l21
-							}
-							position++
-							goto l20
-						l21:
-							position, tokenIndex = position20, tokenIndex20
-							if buffer[position] != rune('\t') {
-								goto l22
-							}
-							position++
-							goto l20
-						l22:
-							position, tokenIndex = position20, tokenIndex20
-							if buffer[position] != rune('\n') {
-								goto l19
-							}
-							position++
+						position++
+						goto l146
+					l148:
+						position, tokenIndex = position146, tokenIndex146
+						if buffer[position] != rune('\n') {
+							goto l145
 						}
-					l20:
-						goto l18
-					l19:
-						position, tokenIndex = position19, tokenIndex19
-					}
-					if buffer[position] != rune('|') {
-						goto l4
+						position++
 					}
-					position++
-				l23:
+				l146:
+					goto l144
+				l145:
+					position, tokenIndex = position145, tokenIndex145
+				}
+				if buffer[position] != rune('|') {
+					goto l142
+				}
+				position++
+			l149:
+				{
+					position150, tokenIndex150 := position, tokenIndex
 					{
-						position24, tokenIndex24 := position, tokenIndex
-						{
-							position25, tokenIndex25 := position, tokenIndex
-							if buffer[position] != rune(' ') {
-								goto l26
-							}
-							position++
-							goto l25
-						l26:
-							position, tokenIndex = position25, tokenIndex25
-							if buffer[position] != rune('\t') {
-								goto l27
-							}
-							position++
-							goto l25
-						l27:
-							position, tokenIndex = position25, tokenIndex25
-							if buffer[position] != rune('\n') {
-								goto l24
-							}
-							position++
+						position151, tokenIndex151 := position, tokenIndex
+						if buffer[position] != rune(' ') {
+							goto l152
+						}
+						position++
+						goto l151
+					l152:
+						position, tokenIndex = position151, tokenIndex151
+						if buffer[position] != rune('\t') {
+							goto l153
 						}
-					l25:
-						goto l23
-					l24:
-						position, tokenIndex = position24, tokenIndex24
+						position++
+						goto l151
+					l153:
+						position, tokenIndex = position151, tokenIndex151
+						if buffer[position] != rune('\n') {
+							goto l150
+						}
+						position++
 					}
+				l151:
+					goto l149
+				l150:
+					position, tokenIndex = position150, tokenIndex150
+				}
+				{
+					position154, tokenIndex154 := position, tokenIndex
 					if !_rules[ruleAlter]() {
-						goto l4
+						goto l155
+					}
+					goto l154
+				l155:
+					position, tokenIndex = position154, tokenIndex154
+					if !_rules[ruleCat]() {
+						goto l142
 					}
 				}
-			l6:
-				add(ruleAlter, position5)
+			l154:
+				add(ruleAlter, position143)
 			}
 			return true
-		l4:
-			position, tokenIndex = position4, tokenIndex4
+		l142:
+			position, tokenIndex = position142, tokenIndex142
 			return false
 		},
-		/* 2 Cat <- <(Point ((' ' / '\t' / '\n')* Cat)?)> */
+		/* 2 Cat <- <((Group / Point) ((' ' / '\t' / '\n')* Cat)?)> */
 		func() bool {
-			position28, tokenIndex28 := position, tokenIndex
+			position156, tokenIndex156 := position, tokenIndex
 			{
-				position29 := position
-				if !_rules[rulePoint]() {
-					goto l28
+				position157 := position
+				{
+					position158, tokenIndex158 := position, tokenIndex
+					if !_rules[ruleGroup]() {
+						goto l159
+					}
+					goto l158
+				l159:
+					position, tokenIndex = position158, tokenIndex158
+					if !_rules[rulePoint]() {
+						goto l156
+					}
 				}
+			l158:
 				{
-					position30, tokenIndex30 := position, tokenIndex
-				l32:
+					position160, tokenIndex160 := position, tokenIndex
+				l162:
 					{
-						position33, tokenIndex33 := position, tokenIndex
+						position163, tokenIndex163 := position, tokenIndex
 						{
-							position34, tokenIndex34 := position, tokenIndex
+							position164, tokenIndex164 := position, tokenIndex
 							if buffer[position] != rune(' ') {
-								goto l35
+								goto l165
 							}
 							position++
-							goto l34
-						l35:
-							position, tokenIndex = position34, tokenIndex34
+							goto l164
+						l165:
+							position, tokenIndex = position164, tokenIndex164
 							if buffer[position] != rune('\t') {
-								goto l36
+								goto l166
 							}
 							position++
-							goto l34
-						l36:
-							position, tokenIndex = position34, tokenIndex34
+							goto l164
+						l166:
+							position, tokenIndex = position164, tokenIndex164
 							if buffer[position] != rune('\n') {
-								goto l33
+								goto l163
 							}
 							position++
 						}
-					l34:
-						goto l32
-					l33:
-						position, tokenIndex = position33, tokenIndex33
+					l164:
+						goto l162
+					l163:
+						position, tokenIndex = position163, tokenIndex163
 					}
 					if !_rules[ruleCat]() {
-						goto l30
+						goto l160
 					}
-					goto l31
-				l30:
-					position, tokenIndex = position30, tokenIndex30
+					goto l161
+				l160:
+					position, tokenIndex = position160, tokenIndex160
 				}
-			l31:
-				add(ruleCat, position29)
+			l161:
+				add(ruleCat, position157)
 			}
 			return true
-		l28:
-			position, tokenIndex = position28, tokenIndex28
+		l156:
+			position, tokenIndex = position156, tokenIndex156
 			return false
 		},
-		/* 3 Point <- <(Concrete (Any / Several / Maybe / LazyAny / LazySeveral / LazyMaybe)?)> */
+		/* 3 Group <- <('(' (' ' / '\t' / '\n')* (Alter / Cat) (' ' / '\t' / '\n')* ')' (LazyAny / LazySeveral / LazyMaybe / Any / Several / Maybe)?)> */
 		func() bool {
-			position37, tokenIndex37 := position, tokenIndex
+			position167, tokenIndex167 := position, tokenIndex
 			{
-				position38 := position
-				if !_rules[ruleConcrete]() {
-					goto l37
+				position168 := position
+				if buffer[position] != rune('(') {
+					goto l167
+				}
+				position++
+			l169:
+				{
+					position170, tokenIndex170 := position, tokenIndex
+					{
+						position171, tokenIndex171 := position, tokenIndex
+						if buffer[position] != rune(' ') {
+							goto l172
+						}
+						position++
+						goto l171
+					l172:
+						position, tokenIndex = position171, tokenIndex171
+						if buffer[position] != rune('\t') {
+							goto l173
+						}
+						position++
+						goto l171
+					l173:
+						position, tokenIndex = position171, tokenIndex171
+						if buffer[position] != rune('\n') {
+							goto l170
+						}
+						position++
+					}
+				l171:
+					goto l169
+				l170:
+					position, tokenIndex = position170, tokenIndex170
+				}
+				{
+					position174, tokenIndex174 := position, tokenIndex
+					if !_rules[ruleAlter]() {
+						goto l175
+					}
+					goto l174
+				l175:
+					position, tokenIndex = position174, tokenIndex174
+					if !_rules[ruleCat]() {
+						goto l167
+					}
 				}
+			l174:
+			l176:
 				{
-					position39, tokenIndex39 := position, tokenIndex
+					position177, tokenIndex177 := position, tokenIndex
 					{
-						position41, tokenIndex41 := position, tokenIndex
+						position178, tokenIndex178 := position, tokenIndex
+						if buffer[position] != rune(' ') {
+							goto l179
+						}
+						position++
+						goto l178
+					l179:
+						position, tokenIndex = position178, tokenIndex178
+						if buffer[position] != rune('\t') {
+							goto l180
+						}
+						position++
+						goto l178
+					l180:
+						position, tokenIndex = position178, tokenIndex178
+						if buffer[position] != rune('\n') {
+							goto l177
+						}
+						position++
+					}
+				l178:
+					goto l176
+				l177:
+					position, tokenIndex = position177, tokenIndex177
+				}
+				if buffer[position] != rune(')') {
+					goto l167
+				}
+				position++
+				{
+					position181, tokenIndex181 := position, tokenIndex
+					{
+						position182, tokenIndex182 := position, tokenIndex
+						if !_rules[ruleLazyAny]() {
+							goto l183
+						}
+						goto l182
+					l183:
+						position, tokenIndex = position182, tokenIndex182
+						if !_rules[ruleLazySeveral]() {
+							goto l184
+						}
+						goto l182
+					l184:
+						position, tokenIndex = position182, tokenIndex182
+						if !_rules[ruleLazyMaybe]() {
+							goto l185
+						}
+						goto l182
+					l185:
+						position, tokenIndex = position182, tokenIndex182
 						if !_rules[ruleAny]() {
-							goto l42
+							goto l186
 						}
-						goto l41
-					l42:
-						position, tokenIndex = position41, tokenIndex41
+						goto l182
+					l186:
+						position, tokenIndex = position182, tokenIndex182
 						if !_rules[ruleSeveral]() {
-							goto l43
+							goto l187
 						}
-						goto l41
-					l43:
-						position, tokenIndex = position41, tokenIndex41
+						goto l182
+					l187:
+						position, tokenIndex = position182, tokenIndex182
 						if !_rules[ruleMaybe]() {
-							goto l44
+							goto l181
 						}
-						goto l41
-					l44:
-						position, tokenIndex = position41, tokenIndex41
+					}
+				l182:
+					goto l188
+				l181:
+					position, tokenIndex = position181, tokenIndex181
+				}
+			l188:
+				add(ruleGroup, position168)
+			}
+			return true
+		l167:
+			position, tokenIndex = position167, tokenIndex167
+			return false
+		},
+		/* 4 Point <- <(Concrete (LazyAny / LazySeveral / LazyMaybe / Any / Several / Maybe)?)> */
+		func() bool {
+			position189, tokenIndex189 := position, tokenIndex
+			{
+				position190 := position
+				if !_rules[ruleConcrete]() {
+					goto l189
+				}
+				{
+					position191, tokenIndex191 := position, tokenIndex
+					{
+						position192, tokenIndex192 := position, tokenIndex
 						if !_rules[ruleLazyAny]() {
-							goto l45
+							goto l193
 						}
-						goto l41
-					l45:
-						position, tokenIndex = position41, tokenIndex41
+						goto l192
+					l193:
+						position, tokenIndex = position192, tokenIndex192
 						if !_rules[ruleLazySeveral]() {
-							goto l46
+							goto l194
 						}
-						goto l41
-					l46:
-						position, tokenIndex = position41, tokenIndex41
+						goto l192
+					l194:
+						position, tokenIndex = position192, tokenIndex192
 						if !_rules[ruleLazyMaybe]() {
-							goto l39
+							goto l195
+						}
+						goto l192
+					l195:
+						position, tokenIndex = position192, tokenIndex192
+						if !_rules[ruleAny]() {
+							goto l196
+						}
+						goto l192
+					l196:
+						position, tokenIndex = position192, tokenIndex192
+						if !_rules[ruleSeveral]() {
+							goto l197
+						}
+						goto l192
+					l197:
+						position, tokenIndex = position192, tokenIndex192
+						if !_rules[ruleMaybe]() {
+							goto l191
 						}
 					}
-				l41:
-					goto l40
-				l39:
-					position, tokenIndex = position39, tokenIndex39
+				l192:
+					goto l198
+				l191:
+					position, tokenIndex = position191, tokenIndex191
 				}
-			l40:
-				add(rulePoint, position38)
+			l198:
+				add(rulePoint, position190)
 			}
 			return true
-		l37:
-			position, tokenIndex = position37, tokenIndex37
+		l189:
+			position, tokenIndex = position189, tokenIndex189
 			return false
 		},
-		/* 4 Concrete <- <(Type ('(' SP Value SP ')')? ((Rect Time?) / (Time? Rect?)))> */
+		/* 5 Concrete <- <(Type ('(' SP Value SP ')')? ((Rect Time?) / (Time? Rect?)))> */
 		func() bool {
 			position47, tokenIndex47 := position, tokenIndex
 			{
@@ -743,7 +839,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position47, tokenIndex47
 			return false
 		},
-		/* 5 Any <- <'*'> */
+		/* 6 Any <- <'*'> */
 		func() bool {
 			position59, tokenIndex59 := position, tokenIndex
 			{
@@ -759,7 +855,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position59, tokenIndex59
 			return false
 		},
-		/* 6 Several <- <'+'> */
+		/* 7 Several <- <'+'> */
 		func() bool {
 			position61, tokenIndex61 := position, tokenIndex
 			{
@@ -775,7 +871,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position61, tokenIndex61
 			return false
 		},
-		/* 7 Maybe <- <'?'> */
+		/* 8 Maybe <- <'?'> */
 		func() bool {
 			position63, tokenIndex63 := position, tokenIndex
 			{
@@ -791,7 +887,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position63, tokenIndex63
 			return false
 		},
-		/* 8 LazyAny <- <('*' '?')> */
+		/* 9 LazyAny <- <('*' '?')> */
 		func() bool {
 			position65, tokenIndex65 := position, tokenIndex
 			{
@@ -811,7 +907,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position65, tokenIndex65
 			return false
 		},
-		/* 9 LazySeveral <- <('+' '?')> */
+		/* 10 LazySeveral <- <('+' '?')> */
 		func() bool {
 			position67, tokenIndex67 := position, tokenIndex
 			{
@@ -831,7 +927,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position67, tokenIndex67
 			return false
 		},
-		/* 10 LazyMaybe <- <('?' '?')> */
+		/* 11 LazyMaybe <- <('?' '?')> */
 		func() bool {
 			position69, tokenIndex69 := position, tokenIndex
 			{
@@ -851,7 +947,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position69, tokenIndex69
 			return false
 		},
-		/* 11 Type <- <Token> */
+		/* 12 Type <- <Token> */
 		func() bool {
 			position71, tokenIndex71 := position, tokenIndex
 			{
@@ -866,13 +962,19 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position71, tokenIndex71
 			return false
 		},
-		/* 12 Value <- <(Number / Char / Token)> */
+		/* 13 Value <- <(Chord / Number / Char / Token)> */
 		func() bool {
 			position73, tokenIndex73 := position, tokenIndex
 			{
 				position74 := position
 				{
 					position75, tokenIndex75 := position, tokenIndex
+					if !_rules[ruleChord]() {
+						goto l204
+					}
+					goto l75
+				l204:
+					position, tokenIndex = position75, tokenIndex75
 					if !_rules[ruleNumber]() {
 						goto l76
 					}
@@ -897,7 +999,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position73, tokenIndex73
 			return false
 		},
-		/* 13 Rect <- <(In / Out / Anywhere)> */
+		/* 14 Rect <- <(In / Out / Anywhere)> */
 		func() bool {
 			position78, tokenIndex78 := position, tokenIndex
 			{
@@ -928,7 +1030,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position78, tokenIndex78
 			return false
 		},
-		/* 14 In <- <(':' 'i' 'n')> */
+		/* 15 In <- <(':' 'i' 'n')> */
 		func() bool {
 			position83, tokenIndex83 := position, tokenIndex
 			{
@@ -952,7 +1054,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position83, tokenIndex83
 			return false
 		},
-		/* 15 Out <- <(':' 'o' 'u' 't')> */
+		/* 16 Out <- <(':' 'o' 'u' 't')> */
 		func() bool {
 			position85, tokenIndex85 := position, tokenIndex
 			{
@@ -980,7 +1082,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position85, tokenIndex85
 			return false
 		},
-		/* 16 Anywhere <- <(':' 'a' 'n' 'y')> */
+		/* 17 Anywhere <- <(':' 'a' 'n' 'y')> */
 		func() bool {
 			position87, tokenIndex87 := position, tokenIndex
 			{
@@ -1008,7 +1110,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position87, tokenIndex87
 			return false
 		},
-		/* 17 Time <- <(Begin / End)> */
+		/* 18 Time <- <(Begin / End)> */
 		func() bool {
 			position89, tokenIndex89 := position, tokenIndex
 			{
@@ -1033,7 +1135,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position89, tokenIndex89
 			return false
 		},
-		/* 18 Begin <- <(':' 'b' 'e' 'g' 'i' 'n')> */
+		/* 19 Begin <- <(':' 'b' 'e' 'g' 'i' 'n')> */
 		func() bool {
 			position93, tokenIndex93 := position, tokenIndex
 			{
@@ -1069,7 +1171,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position93, tokenIndex93
 			return false
 		},
-		/* 19 End <- <(':' 'e' 'n' 'd')> */
+		/* 20 End <- <(':' 'e' 'n' 'd')> */
 		func() bool {
 			position95, tokenIndex95 := position, tokenIndex
 			{
@@ -1097,7 +1199,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position95, tokenIndex95
 			return false
 		},
-		/* 20 Token <- <(('!'? ('_' / [a-z] / [A-Z]) ('_' / [a-z] / [A-Z] / [0-9] / '/' / '.' / '-')*) / '←' / '→' / '↑' / '↓' / '.')> */
+		/* 21 Token <- <(('!'? ('_' / [a-z] / [A-Z]) ('_' / [a-z] / [A-Z] / [0-9] / '/' / '.' / '-')*) / '←' / '→' / '↑' / '↓' / '.')> */
 		func() bool {
 			position97, tokenIndex97 := position, tokenIndex
 			{
@@ -1238,7 +1340,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position97, tokenIndex97
 			return false
 		},
-		/* 21 Number <- <(('-' / '+')? [0-9] ('o' / 'O' / 'x' / 'X')? [0-9]*)> */
+		/* 22 Number <- <(('-' / '+')? [0-9] ('o' / 'O' / 'x' / 'X')? [0-9]*)> */
 		func() bool {
 			position119, tokenIndex119 := position, tokenIndex
 			{
@@ -1323,7 +1425,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position119, tokenIndex119
 			return false
 		},
-		/* 22 Char <- <('\'' . '\'')> */
+		/* 23 Char <- <('\'' . '\'')> */
 		func() bool {
 			position133, tokenIndex133 := position, tokenIndex
 			{
@@ -1346,7 +1448,7 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			position, tokenIndex = position133, tokenIndex133
 			return false
 		},
-		/* 23 SP <- <(' ' / '\t' / '\n')*> */
+		/* 24 SP <- <(' ' / '\t' / '\n')*> */
 		func() bool {
 			{
 				position136 := position
@@ -1383,6 +1485,42 @@ func (p *rpeg) Init(options ...func(*rpeg) error) error {
 			}
 			return true
 		},
+		/* 25 Chord <- <(Token ('+' Token)+)> */
+		func() bool {
+			position200, tokenIndex200 := position, tokenIndex
+			{
+				position201 := position
+				if !_rules[ruleToken]() {
+					goto l200
+				}
+				if buffer[position] != rune('+') {
+					goto l200
+				}
+				position++
+				if !_rules[ruleToken]() {
+					goto l200
+				}
+			l202:
+				{
+					position203, tokenIndex203 := position, tokenIndex
+					if buffer[position] != rune('+') {
+						goto l203
+					}
+					position++
+					if !_rules[ruleToken]() {
+						goto l203
+					}
+					goto l202
+				l203:
+					position, tokenIndex = position203, tokenIndex203
+				}
+				add(ruleChord, position201)
+			}
+			return true
+		l200:
+			position, tokenIndex = position200, tokenIndex200
+			return false
+		},
 	}
 	p.rules = _rules
 	return nil
@@ -0,0 +1,162 @@
+package contraption
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestCompileRegexpChords(t *testing.T) {
+	tests := []struct {
+		pattern Regexp
+		column  int // 0 if the pattern is valid
+	}{
+		{`Hover+ Click(1)`, 0},
+		{`Hover+`, 0},
+		{`Scroll+?`, 0},
+		{`Press(Ctrl+Shift+I)`, 0},
+		{`Press(LCtrl+A)+ Release(A)`, 0},
+		{`Release(Alt+F4)`, 0},
+		{`Press(Super+I)`, 0},
+		{`Press(RSuper+Shift+I)`, 0},
+		{`Press(Ctrl+Foo+I)`, 7},
+		{`Press(Ctrl+Nokey)`, 7},
+		{`Click(Ctrl+A)`, 7},
+		{`Hoverr+`, 1},
+	}
+	for _, tt := range tests {
+		_, err := CompileRegexp(tt.pattern)
+		if tt.column == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tt.pattern, err)
+			}
+			continue
+		}
+		var rerr *RegexpError
+		if !errors.As(err, &rerr) {
+			t.Errorf("%s: want RegexpError, got %v", tt.pattern, err)
+			continue
+		}
+		if rerr.Column != tt.column {
+			t.Errorf("%s: column %d, want %d", tt.pattern, rerr.Column, tt.column)
+		}
+	}
+}
+
+func TestRegexpErrorColumns(t *testing.T) {
+	tests := []struct {
+		pattern Regexp
+		column  int
+		msg     string
+	}{
+		{`Clik(1)`, 1, "unknown event type “Clik”"},
+		{`Click(1) Hoverr`, 10, "unknown event type “Hoverr”"},
+		{`Click(1`, 8, "unexpected end of pattern"},
+		{`(Click(1)`, 10, "unexpected end of pattern"},
+		{`Click(1))`, 9, "unexpected “)”"},
+		{`Press(Foo)`, 7, "no such constant “Foo”"},
+		{`Click(99999999999999999999)`, 7, "value out of range"},
+		{`Drop(text/foo)`, 6, "unsupported MIME type “text/foo”"},
+		{``, 1, "unexpected end of pattern"},
+	}
+	for _, tt := range tests {
+		_, err := CompileRegexp(tt.pattern)
+		var rerr *RegexpError
+		if !errors.As(err, &rerr) {
+			t.Errorf("%s: want RegexpError, got %v", tt.pattern, err)
+			continue
+		}
+		if rerr.Column != tt.column || rerr.Msg != tt.msg || rerr.Pattern != string(tt.pattern) {
+			t.Errorf("%s: column %d: %s, want column %d: %s", tt.pattern, rerr.Column, rerr.Msg, tt.column, tt.msg)
+		}
+	}
+}
+
+// rtrace makes a trace of events from the newest to the oldest, a second apart.
+func rtrace(evs ...any) []EventPoint {
+	epoch := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := make([]EventPoint, len(evs)+1) // With a tail, see (*Events).trueemit
+	for i, e := range evs {
+		tr[i] = EventPoint{E: e, T: epoch.Add(time.Duration(len(evs)-i) * time.Second)}
+	}
+	return tr
+}
+
+func TestRinterp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		trace   []any
+		caps    []int // nil if the pattern is not matched
+	}{
+		{`Click(1)`, []any{Click(1)}, []int{0, 1}},
+		{`Click(1)`, []any{Click(2)}, nil},
+		{`Click(1)`, []any{Hover{}, Click(1)}, nil},
+		{`Click(1) | Click(2)`, []any{Click(2)}, []int{0, 1}},
+		{`Click`, []any{Click(3)}, []int{0, 1}},
+		{`Scroll(-1)`, []any{Scroll(-1)}, []int{0, 1}},
+		{`Press(A)`, []any{Press{Key: KeyA, Rune: 'a'}}, []int{0, 1}},
+		{`Press(Ctrl+A)`, []any{Press{Key: KeyA}}, nil},
+		{`Unclick(1) . Click(1)`, []any{Unclick(1), Hover{}, Click(1)}, []int{0, 3}},
+		{`Unclick(1) !Click(1)* Click(1)`, []any{Unclick(1), Hover{}, Click(2), Click(1)}, []int{0, 4}},
+		{`Unclick(1) !Click(1)* Click(1)`, []any{Unclick(1), Click(1), Hover{}, Click(1)}, []int{0, 2}},
+		{`Click(1) Hover+`, []any{Click(1), Click(1)}, nil},
+		{`Click(1) Hover+`, []any{Click(1), Hover{}, Hover{}, Scroll(1)}, []int{0, 3}},
+		{`Click(1) Hover?`, []any{Click(1), Scroll(1)}, []int{0, 1}},
+
+		// Groups.
+		{`(Unclick(1) Click(1))+`, []any{Unclick(1), Click(1), Unclick(1), Click(1)}, []int{0, 4, 2, 4}},
+		{`Hover (Click(1))?`, []any{Hover{}}, []int{0, 1, -1, -1}},
+		{`(Hover)(Click(1))`, []any{Hover{}, Click(1)}, []int{0, 2, 0, 1, 1, 2}},
+		{`((Hover) Click(1))`, []any{Hover{}, Click(1)}, []int{0, 2, 0, 2, 0, 1}},
+		{`(Hover | Click(1))+`, []any{Hover{}, Click(1), Scroll(1)}, []int{0, 2, 1, 2}},
+
+		// Greedy and lazy quantifiers.
+		{`(Hover*)(Hover*) Click(1)`, []any{Hover{}, Hover{}, Click(1)}, []int{0, 3, 0, 2, 2, 2}},
+		{`(Hover*?)(Hover*) Click(1)`, []any{Hover{}, Hover{}, Click(1)}, []int{0, 3, 0, 0, 0, 2}},
+		{`(Hover+)(Hover*) Click(1)`, []any{Hover{}, Hover{}, Click(1)}, []int{0, 3, 0, 2, 2, 2}},
+		{`(Hover+?)(Hover*) Click(1)`, []any{Hover{}, Hover{}, Click(1)}, []int{0, 3, 0, 1, 1, 2}},
+		{`(Hover?)(Hover*) Click(1)`, []any{Hover{}, Hover{}, Click(1)}, []int{0, 3, 0, 1, 1, 2}},
+		{`(Hover??)(Hover*) Click(1)`, []any{Hover{}, Hover{}, Click(1)}, []int{0, 3, 0, 0, 0, 2}},
+		{`Click(1) (.*)`, []any{Click(1), Hover{}, Hover{}}, []int{0, 3, 1, 3}},
+		{`Click(1) (.*?)`, []any{Click(1), Hover{}, Hover{}}, []int{0, 1, 1, 1}},
+		{`(.*)*`, []any{Hover{}}, []int{0, 1, 0, 1}},
+	}
+	for _, tt := range tests {
+		v, err := rcompile(tt.pattern)
+		if err != nil {
+			t.Errorf("%s: %v", tt.pattern, err)
+			continue
+		}
+		m := Matcher{dur: time.Duration(math.MaxInt64)}
+		ok, _, caps := rinterp(v, rtrace(tt.trace...), m, 2*rgroups(v))
+		if ok != (tt.caps != nil) || !slices.Equal(caps, tt.caps) {
+			t.Errorf("%s: matched %v %v, want %v", tt.pattern, ok, caps, tt.caps)
+		}
+	}
+}
+
+func TestRinterpDuration(t *testing.T) {
+	v, err := rcompile(`Click(1) .* Unclick(1) Click(1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A double click happens over 3 seconds of the trace, an older one over 5.
+	tr := rtrace(Click(1), Hover{}, Unclick(1), Click(1), Unclick(1), Click(1))
+	for _, tt := range []struct {
+		dur  time.Duration
+		ok   bool
+		caps []int
+	}{
+		{time.Second, false, nil},
+		{3 * time.Second, true, []int{0, 4}},
+		{10 * time.Second, true, []int{0, 6}},
+	} {
+		ok, _, caps := rinterp(v, tr, Matcher{dur: tt.dur}, 2)
+		if ok != tt.ok || !slices.Equal(caps, tt.caps) {
+			t.Errorf("in %v: matched %v %v, want %v %v", tt.dur, ok, caps, tt.ok, tt.caps)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Package regexpcheck finds event regexps in Go sources and reports errors in them,
+// like go vet does with format strings.
+//
+// Packages are type-checked, and string literals are checked if they are passed as a Regexp
+// to a function or a method of contraption, like Match of Matcher and Events,
+// CompileRegexp and MustCompileRegexp. Functions of other packages with the same names,
+// like (*regexp.Regexp).MatchString, are skipped.
+//
+// It is meant to be called from a test of a package that uses contraption:
+//
+//	func TestRegexps(t *testing.T) {
+//		regexpcheck.Test(t, ".")
+//	}
+package regexpcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/neputevshina/contraption"
+)
+
+const contraptionPath = "github.com/neputevshina/contraption"
+
+// Problem is an invalid regexp in a source.
+type Problem struct {
+	// Pos is the position of the error in the source, if the literal has no escapes.
+	// Otherwise it is the position of the literal.
+	Pos token.Position
+	Err *contraption.RegexpError
+}
+
+func (p Problem) String() string {
+	return p.Pos.String() + ": " + p.Err.Error()
+}
+
+// Test reports every Problem in the Go files of dirs as an error of t.
+func Test(t testing.TB, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		ps, err := Dir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range ps {
+			t.Error(p)
+		}
+	}
+}
+
+// listed is a package listed by go list.
+type listed struct {
+	ImportPath   string
+	Dir          string
+	Export       string
+	GoFiles      []string
+	CgoFiles     []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+}
+
+// golist runs go list in dir with args and decodes the listed packages.
+func golist(dir string, args ...string) ([]listed, error) {
+	cmd := exec.Command("go", append([]string{"list", "-e", "-json"}, args...)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("regexpcheck: go list: %v: %s", err, stderr.Bytes())
+	}
+	var ls []listed
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var l listed
+		if err := dec.Decode(&l); errors.Is(err, io.EOF) {
+			return ls, nil
+		} else if err != nil {
+			return nil, err
+		}
+		ls = append(ls, l)
+	}
+}
+
+// Dir checks regexps in the Go package in dir, including its tests.
+//
+// Imported packages are built by the go command to get their types, so Dir
+// must be called inside of a module that requires contraption.
+func Dir(dir string) ([]Problem, error) {
+	pkgs, err := golist(dir, ".")
+	if err != nil {
+		return nil, err
+	}
+	deps, err := golist(dir, "-deps", "-test", "-export", ".")
+	if err != nil {
+		return nil, err
+	}
+	// Test variants of packages, like “p [p.test]”, are listed too, but only
+	// plain packages are imported by their paths.
+	exports := map[string]string{}
+	for _, d := range deps {
+		if _, ok := exports[d.ImportPath]; !ok && d.Export != "" {
+			exports[d.ImportPath] = d.Export
+		}
+	}
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		e, ok := exports[path]
+		if !ok {
+			return nil, fmt.Errorf("regexpcheck: no export data of %s", path)
+		}
+		return os.Open(e)
+	})
+
+	var ps []Problem
+	for _, p := range pkgs {
+		for _, names := range [][]string{append(append(p.GoFiles, p.CgoFiles...), p.TestGoFiles...), p.XTestGoFiles} {
+			if len(names) == 0 {
+				continue
+			}
+			var files []*ast.File
+			for _, name := range names {
+				f, err := parser.ParseFile(fset, filepath.Join(p.Dir, name), nil, parser.SkipObjectResolution)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, f)
+			}
+			info := &types.Info{Uses: map[*ast.Ident]types.Object{}}
+			conf := types.Config{
+				Importer:    imp,
+				FakeImportC: true,
+				// Calls that can't be typed are skipped, so errors don't matter.
+				Error: func(error) {},
+			}
+			conf.Check(p.ImportPath, fset, files, info)
+			ps = append(ps, Check(fset, files, info)...)
+		}
+	}
+	return ps, nil
+}
+
+// Check checks regexps in type-checked files. info must have Uses.
+func Check(fset *token.FileSet, files []*ast.File, info *types.Info) (ps []Problem) {
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 || !takesRegexp(info, call) {
+				return true
+			}
+			lit := literal(call.Args[0])
+			if lit == nil {
+				return true
+			}
+			pattern, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			if _, err := contraption.CompileRegexp(contraption.Regexp(pattern)); err != nil {
+				rerr := err.(*contraption.RegexpError)
+				ps = append(ps, Problem{Pos: position(fset, lit, pattern, rerr.Column), Err: rerr})
+			}
+			return true
+		})
+	}
+	return
+}
+
+// takesRegexp reports if call is a call of a function or a method of contraption
+// which first parameter is a Regexp.
+func takesRegexp(info *types.Info, call *ast.CallExpr) bool {
+	fun := call.Fun
+	for {
+		p, ok := fun.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		fun = p.X
+	}
+	var id *ast.Ident
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return false
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != contraptionPath {
+		return false
+	}
+	params := fn.Type().(*types.Signature).Params()
+	if params.Len() == 0 {
+		return false
+	}
+	named, ok := params.At(0).Type().(*types.Named)
+	return ok && named.Obj().Name() == "Regexp" && named.Obj().Pkg() == fn.Pkg()
+}
+
+// literal returns the string literal in e, which may be converted to Regexp.
+func literal(e ast.Expr) *ast.BasicLit {
+	for {
+		switch x := e.(type) {
+		case *ast.ParenExpr:
+			e = x.X
+		case *ast.CallExpr:
+			if len(x.Args) != 1 {
+				return nil
+			}
+			switch fun := x.Fun.(type) {
+			case *ast.Ident:
+				if fun.Name != "Regexp" {
+					return nil
+				}
+			case *ast.SelectorExpr:
+				if fun.Sel.Name != "Regexp" {
+					return nil
+				}
+			default:
+				return nil
+			}
+			e = x.Args[0]
+		case *ast.BasicLit:
+			if x.Kind != token.STRING {
+				return nil
+			}
+			return x
+		default:
+			return nil
+		}
+	}
+}
+
+// position returns the position of the column of the pattern in the literal.
+func position(fset *token.FileSet, lit *ast.BasicLit, pattern string, column int) token.Position {
+	pos := fset.Position(lit.Pos())
+	if lit.Value[1:len(lit.Value)-1] != pattern {
+		// There are escapes, so columns of the source and the pattern differ.
+		return pos
+	}
+	pos.Column++ // Quote
+	for i, r := range pattern {
+		if utf8.RuneCountInString(pattern[:i]) == column-1 {
+			break
+		}
+		if r == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column += utf8.RuneLen(r)
+		}
+		pos.Offset += utf8.RuneLen(r)
+	}
+	pos.Offset++
+	return pos
+}
@@ -0,0 +1,29 @@
+package regexpcheck
+
+import "testing"
+
+// TestDir checks testdata/p, where calls of other Match functions must be skipped.
+func TestDir(t *testing.T) {
+	ps, err := Dir("testdata/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line, column int
+		msg          string
+	}{
+		{18, 23, "unexpected end of pattern"},
+		{19, 15, "unknown event type “Clak”"},
+		{20, 62, "no such constant “Foo”"},
+		{10, 47, "unexpected “|”"},
+	}
+	if len(ps) != len(want) {
+		t.Fatalf("%d problems: %v, want %d", len(ps), ps, len(want))
+	}
+	for i, p := range ps {
+		w := want[i]
+		if p.Pos.Line != w.line || p.Pos.Column != w.column || p.Err.Msg != w.msg {
+			t.Errorf("problem %v, want %d:%d: %s", p, w.line, w.column, w.msg)
+		}
+	}
+}
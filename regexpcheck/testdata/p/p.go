@@ -0,0 +1,21 @@
+package p
+
+import (
+	"regexp"
+
+	"github.com/neputevshina/contraption"
+)
+
+var validID = regexp.MustCompile(`^[a-z]+\[[0-9]+\]$`)
+
+// Match is not the one of contraption.
+func Match(s string) bool { return s != "" }
+
+func f(wo *contraption.World, m contraption.Matcher) {
+	_ = validID.MatchString("adam[23]")
+	_ = Match("Click(1")
+	_ = wo.Match(`Click(1) Hover+`)
+	_ = wo.Match(`Click(1`)
+	_ = m.Match(`Clak(1)`)
+	_ = contraption.MustCompileRegexp(contraption.Regexp(`Press(Foo)`))
+}
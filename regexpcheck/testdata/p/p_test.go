@@ -0,0 +1,11 @@
+package p
+
+import (
+	"testing"
+
+	"github.com/neputevshina/contraption"
+)
+
+func TestP(t *testing.T) {
+	_, _ = contraption.CompileRegexp("Scroll(+1) |")
+}
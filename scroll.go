@@ -0,0 +1,610 @@
+package contraption
+
+import (
+	"math"
+	"time"
+
+	"github.com/neputevshina/geom"
+)
+
+// Parameters of smooth scrolling.
+const (
+	scrollEase     = 50 * time.Millisecond  // Time constant of the interpolation of wheel steps
+	scrollFriction = 325 * time.Millisecond // Time constant of the decay of momentum
+	scrollBurst    = 80 * time.Millisecond  // Events closer than this make a burst
+	scrollMomentum = 3                      // Events in a burst to leave momentum
+	scrollHide     = 800 * time.Millisecond // Scrollbars are hidden after this much idle time
+)
+
+// scrollaligner is followaligner for compounds with Hscroll or Vscroll.
+//
+// Kids are laid out from the one at the Index of a compound, elements of Sequences are
+// requested by as many as are estimated to fill the limit until it is filled, so a compound
+// of any length is laid out in constant time. Kids that are not from Sequences are always laid out, but are put
+// out of sight if they are not seen. Kids don't stretch along the main axis.
+//
+// Kids that are shorter than the limit are aligned by Valign or Halign along the main axis.
+// If they are aligned to the end, the compound is a chat: its Index is counted from the end.
+func scrollaligner(wo *World, c *Sorm, h bool) {
+	beginaxis, endaxis := axis(h)
+	l := c.l
+	ax, ay := selfalignment(wo, c)
+	if h {
+		swapxy(&l)
+		ay = ax
+	}
+	kids := c.kids2(wo)
+	idx := c.idx
+	wo.saveindex(idx)
+	idx.chat = ay == 1
+
+	// Index counts elements of Sequences as kids.
+	total := 0
+	for _, k := range kids {
+		if q, ok := k.key.(Sequence); ok {
+			total += q.Length(wo)
+		} else {
+			total++
+		}
+	}
+	idx.I = max(0, min(idx.I, total-1))
+	// Kid at the Index, counted from the start.
+	at := cond(idx.chat, total-1-idx.I, idx.I)
+
+	type laid struct {
+		k    *Sorm
+		i    int
+		y    float64
+		size float64
+	}
+	var window []laid
+	var before []*Sorm // Kids before the start that are not from Sequences
+
+	place := func(k *Sorm) {
+		wo.prepasskid(c, k)
+		wo.divide(k)
+		wo.applykid(c, k)
+		stretch := false
+		beginaxis(k)
+		// Y is the main axis, X is perpendicular.
+		e := stretchof(k)
+		if e.Y > 0 {
+			k.Size.Y = 0
+			k.l.Y = 0
+			stretch = true
+		}
+		if e.X > 0 {
+			k.Size.X = l.X
+			k.l.X = k.Size.X
+			stretch = true
+		}
+		endaxis(k)
+		if stretch {
+			wo.apply(c, k)
+		}
+	}
+
+	// Elements are requested by as many as are estimated to fill the viewport.
+	var chunk [32]*Sorm
+	buf := chunk[:1]
+	if idx.avg > 0 {
+		buf = chunk[:int(clamp(1, math.Ceil(min(l.Y, 1e6)/idx.avg)+1, float64(len(chunk))))]
+	}
+
+	// lay lays kids out from the start-th and returns the position of the viewport
+	// relative to the start, the length of laid out kids and if all kids after the start were laid out.
+	lay := func(start int) (top, y float64, end bool) {
+		window, before = window[:0], before[:0]
+		top, end = idx.O, true
+		n := 0
+		visit := func(k *Sorm) {
+			place(k)
+			beginaxis(k)
+			if n < start {
+				before = append(before, k)
+			} else {
+				if n == at {
+					if idx.chat {
+						top = y + k.Size.Y - idx.O - l.Y
+					} else {
+						top = y + idx.O
+					}
+				}
+				window = append(window, laid{k: k, i: n, y: y, size: k.Size.Y})
+				y += k.Size.Y
+			}
+			endaxis(k)
+			n++
+		}
+		seen := func() bool {
+			return n > at && y >= top+l.Y
+		}
+
+		for _, k := range kids {
+			q, ok := k.key.(Sequence)
+			if !ok {
+				visit(k)
+				continue
+			}
+			base, length := n, q.Length(wo)
+			from := min(length, max(0, start-n))
+			if seen() {
+				end = end && from >= length
+				from = length
+			}
+			n += from
+			k.flags &^= flagSequenceSaved
+			wo.materialize(k, q, from, buf, func(e *Sorm) bool {
+				visit(e)
+				if seen() && n < base+length {
+					end = false
+					return false
+				}
+				return true
+			})
+			n = base + length
+		}
+		return
+	}
+
+	// If the viewport starts before the kid at the Index, lay kids out again from as many kids
+	// before it as are estimated to fill the gap. Elements materialized by the previous try are dropped.
+	mark := len(wo.auxpool)
+	start := at
+	var want, top, y float64
+	var end bool
+	for {
+		want, y, end = lay(start)
+		top = want
+		if end {
+			// Don't scroll past the last kid.
+			top = min(top, y-l.Y)
+		}
+		if top >= 0 || start == 0 {
+			break
+		}
+		// Kids before the one at the Index estimate the ones before them best.
+		sum, n := 0.0, 0
+		for _, w := range window {
+			if w.i < at {
+				sum += w.size
+				n++
+			}
+		}
+		avg := idx.avg
+		if n > 0 {
+			avg = sum / float64(n)
+		} else if avg <= 0 && len(window) > 0 {
+			avg = y / float64(len(window))
+		}
+		back := start
+		if avg > 0 {
+			back = int(math.Ceil(min(-top, 1e9)/avg)) + 1
+		}
+		wo.auxpool = wo.auxpool[:mark]
+		for _, k := range kids {
+			wo.forget(k, mark)
+		}
+		start = max(0, start-back)
+	}
+	top = max(0, top)
+	if top != want {
+		// Stop at the ends.
+		idx.rest, idx.v = 0, 0
+	}
+
+	idx.I, idx.O = 0, 0
+	if idx.chat {
+		bottom := min(top+l.Y, y)
+		for _, w := range window {
+			if w.y >= bottom {
+				break
+			}
+			idx.I, idx.O = total-1-w.i, w.y+w.size-bottom
+		}
+	} else {
+		for _, w := range window {
+			if w.y > top {
+				break
+			}
+			idx.I, idx.O = w.i, top-w.y
+		}
+	}
+	idx.end = end && top+l.Y >= y-0.5
+
+	// Align kids that are shorter than the limit.
+	off := 0.0
+	if ay > 0 && !math.IsInf(l.Y, 1) {
+		off = max(0, l.Y-(y-top)) * ay
+	}
+
+	size := point{}
+	yb := 0.0
+	for i := len(before) - 1; i >= 0; i-- {
+		k := before[i]
+		beginaxis(k)
+		yb -= k.Size.Y
+		k.p.Y = yb - top + off
+		size.X = max(size.X, k.Size.X)
+		endaxis(k)
+	}
+	for _, w := range window {
+		beginaxis(w.k)
+		w.k.p.Y = w.y - top + off
+		size.X = max(size.X, w.k.Size.X)
+		endaxis(w.k)
+	}
+	size.Y = min(y-top, l.Y)
+	if off > 0 {
+		size.Y = l.Y
+	}
+	if h {
+		swapxy(&size)
+	}
+	c.Size = size
+
+	idx.view = l.Y
+	idx.at, idx.seen = 0, 0
+	sum := 0.0
+	for _, w := range window {
+		sum += w.size
+		if w.size == 0 {
+			continue
+		}
+		if w.y <= top {
+			idx.at = float64(w.i) + (top-w.y)/w.size
+		}
+		idx.seen += max(0, min(w.y+w.size, top+l.Y)-max(w.y, top)) / w.size
+	}
+	if len(window) > 0 {
+		if mean := sum / float64(len(window)); idx.avg == 0 {
+			idx.avg = mean
+		} else {
+			idx.avg += (mean - idx.avg) / 8
+		}
+	}
+}
+
+// lazy reports if kids of a scrolled compound are laid out by scrollaligner, only when they are seen.
+func (c *Sorm) lazy() bool {
+	return c.idx != nil && (c.aligner == alignerHfollow || c.aligner == alignerVfollow)
+}
+
+// hscrolled reports if a scrolled compound is scrolled horizontally.
+func (c *Sorm) hscrolled() bool {
+	if c.lazy() {
+		return c.aligner == alignerHfollow
+	}
+	return c.flags&flagHscroll > 0
+}
+
+// scrollshift scrolls a compound which aligner is not Hfollow or Vfollow. All its kids are
+// laid out by the aligner, so they are moved to the viewport starting at the kid at the Index.
+func scrollshift(wo *World, c *Sorm) {
+	h := c.hscrolled()
+	beginaxis, endaxis := axis(h)
+	idx := c.idx
+	wo.saveindex(idx)
+	idx.chat = false
+
+	var kids []*Sorm
+	c.kidsiter(wo, kiargs{}, func(k *Sorm) {
+		kids = append(kids, k)
+	})
+	beginaxis(c)
+	defer endaxis(c)
+	l, size := c.l.Y, c.Size.Y
+	if len(kids) == 0 {
+		idx.I, idx.O, idx.at, idx.seen, idx.end = 0, 0, 0, 0, true
+		return
+	}
+	p := make([]float64, len(kids))
+	s := make([]float64, len(kids))
+	for i, k := range kids {
+		beginaxis(k)
+		p[i], s[i] = k.p.Y, k.Size.Y
+		endaxis(k)
+	}
+
+	idx.I = max(0, min(idx.I, len(kids)-1))
+	want := p[idx.I] + idx.O
+	top := max(0, min(want, size-l))
+	if top != want {
+		// Stop at the ends.
+		idx.rest, idx.v = 0, 0
+	}
+	// The Index is at the first kid of the last line that starts before the viewport.
+	idx.I = 0
+	for i := range kids {
+		if p[i] <= top && p[i] > p[idx.I] {
+			idx.I = i
+		}
+	}
+	idx.O = top - p[idx.I]
+	idx.end = top+l >= size-0.5
+
+	idx.view = l
+	idx.avg = size / float64(len(kids))
+	idx.at, idx.seen = 0, 0
+	for i, k := range kids {
+		if s[i] > 0 {
+			if p[i] <= top {
+				idx.at = max(idx.at, float64(i)+min(1, (top-p[i])/s[i]))
+			}
+			idx.seen += max(0, min(p[i]+s[i], top+l)-max(p[i], top)) / s[i]
+		}
+		beginaxis(k)
+		k.p.Y -= top
+		endaxis(k)
+	}
+	c.Size.Y = min(size, l)
+}
+
+// scroll passes wheel events and drags with the middle button to the Index of a scrolled compound.
+func (wo *World) scroll(c *Sorm, m Matcher) {
+	ev := wo.Events.Trace[0]
+	idx := c.idx
+	idx.rect = m.Rect()
+	switch {
+	case c.scrolld.Y != 0 && m.Match(`Scroll:in`):
+		idx.push(float64(ev.E.(Scroll))*c.scrolld.Y, ev.T, false)
+	case c.scrolld.X != 0 && m.Match(`Sweep:in`):
+		idx.push(float64(ev.E.(Sweep))*c.scrolld.X, ev.T, false)
+	case m.Match(`Hover !Unclick(2)* Click(2):in`):
+		d := wo.Events.Trace[1].Pt.Sub(ev.Pt)
+		idx.dragging = true
+		idx.push(cond(c.hscrolled(), d.X, d.Y), ev.T, true)
+	case idx.dragging && wo.Events.Match(`Unclick(2)`):
+		idx.dragging = false
+		if ev.T.Sub(idx.last) > scrollBurst {
+			// Cursor was held still before the release.
+			idx.v = 0
+		}
+	default:
+		return
+	}
+	wo.Events.animate()
+}
+
+// push scrolls the Index by d at the time t, immediately or smoothly.
+func (idx *Index) push(d float64, t time.Time, immediate bool) {
+	if idx.chat {
+		d = -d
+	}
+	if gap := t.Sub(idx.last); gap > 0 && gap < scrollBurst {
+		idx.v = (idx.v + d/gap.Seconds()) / 2
+		idx.burst++
+	} else {
+		idx.v, idx.burst = 0, 1
+	}
+	idx.last = t
+	if immediate {
+		idx.O += d
+	} else {
+		idx.rest += d
+	}
+}
+
+// advance moves the Index for the time passed since the previous frame.
+//
+// The rest of the distance decays exponentially, so wheel steps are smooth.
+// After a burst of events ends, the Index keeps its velocity, which decays by friction.
+// Frames come without waiting for events until the motion stops.
+func (idx *Index) advance(wo *World) {
+	now := wo.Events.Now
+	if now.Equal(idx.t) {
+		return
+	}
+	dt := min(now.Sub(idx.t).Seconds(), 0.1)
+	idx.t = now
+	if idx.rest == 0 && idx.v == 0 {
+		return
+	}
+
+	step := idx.rest * (1 - math.Exp(-dt/scrollEase.Seconds()))
+	if math.Abs(idx.rest-step) < 0.5 {
+		step = idx.rest
+	}
+	idx.O += step
+	idx.rest -= step
+	if idx.burst >= scrollMomentum && !idx.dragging && now.Sub(idx.last) > scrollBurst {
+		idx.O += idx.v * dt
+		idx.v *= math.Exp(-dt / scrollFriction.Seconds())
+	}
+	if math.Abs(idx.v) < 10 {
+		idx.v = 0
+	}
+	wo.Events.animate()
+}
+
+// Scrollmode selects how a scrollbar measures the content.
+type Scrollmode int
+
+const (
+	// ScrollUniform counts kids, the thumb is as long as the share of seen kids.
+	ScrollUniform Scrollmode = iota
+	// ScrollEstimate estimates the length of the content from the average size of seen kids,
+	// so the thumb doesn't jump when kids of different sizes come into view.
+	ScrollEstimate
+)
+
+type scrolltrack struct {
+	idx *Index
+	h   bool
+}
+
+// scrollthumb is the drag value of a scrollbar thumb.
+type scrollthumb struct {
+	idx    *Index
+	h      bool
+	length int
+	mode   Scrollmode
+}
+
+// Vscrollbar returns a vertical scrollbar of width w for a compound scrolled with idx,
+// where length is the count of its kids, as Index counts them.
+//
+// The thumb can be dragged, a click on the track scrolls by a page.
+// Scrollbar is shown only while the compound or the scrollbar is hovered or scrolled.
+// Fill paints the thumb.
+func (wo *World) Vscrollbar(w float64, idx *Index, length int, mode Scrollmode) *Sorm {
+	return wo.scrollbar(w, scrollthumb{idx: idx, length: length, mode: mode})
+}
+
+// Hscrollbar is Vscrollbar for Hscroll, h is its height.
+func (wo *World) Hscrollbar(h float64, idx *Index, length int, mode Scrollmode) *Sorm {
+	return wo.scrollbar(h, scrollthumb{idx: idx, h: true, length: length, mode: mode})
+}
+
+func (wo *World) scrollbar(w float64, t scrollthumb) *Sorm {
+	AddDrag(wo, func(interval [2]geom.Point, t scrollthumb) *Sorm {
+		t.drag(wo, interval)
+		return nil
+	})
+	idx := t.idx
+	track := scrolltrack{idx, t.h}
+	tr := wo.Prevkey(track).Rectangle()
+	th := wo.Prevkey(t).Rectangle()
+	f, frac := t.thumb()
+	n := cond(t.h, tr.Dx(), tr.Dy())
+	size := min(n, max(frac*n, 2*w))
+
+	show := wo.drag == t || idx.rest != 0 || idx.v != 0 ||
+		wo.Events.Now.Sub(idx.last) < scrollHide ||
+		wo.Events.MatchInFreshness(`!Hover* Hover:in`, idx.rect.Union(tr), scrollHide)
+	if show {
+		// Frames must come to hide it.
+		wo.Events.animate()
+	}
+
+	var align, rail, thumb *Sorm
+	if t.h {
+		align, rail = wo.Halign(f), wo.Void(-1, complex(w, 0))
+	} else {
+		align, rail = wo.Valign(f), wo.Void(complex(w, 0), -1)
+	}
+	if show && frac < 1 {
+		thumb = wo.Compound(
+			wo.Identity(t),
+			wo.Source(),
+			cond(t.h, wo.Rectangle(complex(size, 0), complex(w, 0)), wo.Rectangle(complex(w, 0), complex(size, 0))))
+	}
+	return wo.Compound(
+		wo.Identity(track),
+		align,
+		wo.Cond(func(m Matcher) {
+			// Don't choke the thumb.
+			if !show || !m.Nochoke().Match(`Click(1):in`) {
+				return
+			}
+			ev := wo.Events.Trace[0]
+			switch p, q := cond(t.h, ev.Pt.X, ev.Pt.Y), cond(t.h, th.Min.X, th.Min.Y); {
+			case ev.Pt.In(th):
+				idx.grab = f
+			case p < q:
+				idx.push(-idx.view, ev.T, false)
+			default:
+				idx.push(idx.view, ev.T, false)
+			}
+		}),
+		rail,
+		thumb)
+}
+
+// thumb returns the position of the thumb and its length, both as fractions of the track.
+func (t scrollthumb) thumb() (f, frac float64) {
+	idx := t.idx
+	switch t.mode {
+	case ScrollEstimate:
+		content := idx.avg * float64(t.length)
+		f = idx.at * idx.avg / (content - idx.view)
+		frac = idx.view / content
+	default:
+		f = idx.at / (float64(t.length) - idx.seen)
+		frac = idx.seen / float64(t.length)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		f = 0
+	}
+	if math.IsNaN(frac) {
+		frac = 1
+	}
+	return clamp(0, f, 1), clamp(0, frac, 1)
+}
+
+// drag moves the Index to follow the thumb dragged over the interval.
+func (t scrollthumb) drag(wo *World, interval [2]geom.Point) {
+	idx := t.idx
+	tr := wo.Prevkey(scrolltrack{idx, t.h}).Rectangle()
+	th := wo.Prevkey(t).Rectangle()
+	d := interval[1].Sub(interval[0])
+	free := cond(t.h, tr.Dx()-th.Dx(), tr.Dy()-th.Dy())
+	if free <= 0 {
+		return
+	}
+	f := clamp(0, idx.grab+cond(t.h, d.X, d.Y)/free, 1)
+
+	var pos float64 // In kids
+	switch t.mode {
+	case ScrollEstimate:
+		pos = f * max(0, idx.avg*float64(t.length)-idx.view) / idx.avg
+	default:
+		pos = f * max(0, float64(t.length)-idx.seen)
+	}
+	if math.IsNaN(pos) {
+		return
+	}
+	if idx.chat {
+		// Index is at the kid where the viewport ends.
+		pos += idx.seen
+		i := min(int(pos), t.length-1)
+		idx.I = max(0, t.length-1-i)
+		idx.O = (float64(i+1) - pos) * idx.avg
+	} else {
+		idx.I = int(pos)
+		idx.O = (pos - float64(idx.I)) * idx.avg
+	}
+	idx.rest, idx.v = 0, 0
+}
+
+// End reports if the last kid of the scrolled compound is seen whole.
+// Chats stay at the end when kids are added to it.
+func (idx *Index) End() bool {
+	return idx.end
+}
+
+// Shift moves the Index by n kids. Call it after n kids were inserted between the kid at the Index
+// and the start it is counted from, so they stay in place. In chats it is counted from the end.
+func (idx *Index) Shift(n int) {
+	idx.I = max(0, idx.I+n)
+}
+
+// forget makes Sequences and line decorations in the subtree of k that were made after
+// the aux pool had n elements to be made again.
+func (wo *World) forget(k *Sorm, n int) {
+	if k.tag == tagSequence {
+		if k.kidsl >= n {
+			k.flags &^= flagSequenceSaved
+		} else if k.flags&flagSequenceSaved > 0 {
+			pop := wo.beginvirtual()
+			for _, e := range wo.pool[k.presl:k.presr] {
+				wo.forget(e, n)
+			}
+			wo.endvirtual(pop)
+		}
+		return
+	}
+	if k.tag != 0 {
+		return
+	}
+	if sameslice(wo.pool, wo.auxpool) {
+		for _, m := range k.pres(wo) {
+			if m.decorated() && m.kidsl >= n {
+				m.flags &^= flagSequenceSaved
+			}
+		}
+	}
+	for _, k := range k.kids2(wo) {
+		wo.forget(k, n)
+	}
+}
@@ -0,0 +1,75 @@
+package contraption
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neputevshina/geom"
+)
+
+// stillwindower is a Windower without events, for tests that need a World inside of the package.
+type stillwindower struct{}
+
+func (stillwindower) SetupInputCallbacks(func(ev any, pt geom.Point, t time.Time), *Events) {}
+func (stillwindower) PollEvents(*Events)                                                    {}
+func (stillwindower) WaitEvents(*Events)                                                    {}
+func (stillwindower) Develop(*Events)                                                       {}
+func (stillwindower) Now() time.Time                                                        { return time.Unix(0, 0) }
+func (stillwindower) Next(*Events) (ok bool, w, h int, scale float64)                       { return true, 100, 100, 1 }
+
+type nullrenderer struct{}
+
+func (nullrenderer) Run(*Context) {}
+
+// TestEqualScroll checks that the measuring layout of Hequal doesn't change Indices.
+func TestEqualScroll(t *testing.T) {
+	run := func(equal bool) Index {
+		wo := New(stillwindower{}, nullrenderer{}, Config{})
+		key := wo.Eqkey()
+		idx := &Index{I: 20}
+		q := AdhocSequence(func(i int) *Sorm {
+			return wo.Compound(wo.Void(50, complex(float64(5+i*i%11*2), 0)))
+		}, func() int { return 1000 })
+		for i := 0; i < 5 && wo.Next(); i++ {
+			var eq *Sorm
+			if equal {
+				eq = wo.Compound(wo.Hequal(key), wo.Void(10, 10))
+			}
+			wo.Root(wo.Compound(wo.Vfollow(), eq,
+				wo.Compound(wo.Vfollow(), wo.Vscroll(idx, 10), wo.Crop(), wo.Limit(100, 80), wo.Sequence(q))))
+			wo.Develop()
+			idx.I += 7
+		}
+		return *idx
+	}
+	a, b := run(false), run(true)
+	if a.I != b.I || a.O != b.O || a.at != b.at || a.seen != b.seen || a.avg != b.avg {
+		t.Errorf("Index is %+v with Hequal, want %+v", b, a)
+	}
+}
+
+// TestScrollBack checks that the viewport before the kid at the Index is filled in a few tries
+// and elements of the dropped tries are not kept.
+func TestScrollBack(t *testing.T) {
+	wo := New(stillwindower{}, nullrenderer{}, Config{})
+	idx := &Index{I: 500, O: -95}
+	made := 0
+	q := AdhocSequence(func(i int) *Sorm {
+		made++
+		// The kid at the Index is larger than the ones before it, so the first estimate is short.
+		return wo.Compound(wo.Void(50, complex(cond(i == 500, 50., 5.), 0)))
+	}, func() int { return 1000 })
+	wo.Next()
+	wo.Root(wo.Compound(wo.Vfollow(), wo.Vscroll(idx, 10), wo.Crop(), wo.Limit(100, 100), wo.Sequence(q)))
+	wo.Develop()
+	if idx.I != 481 || idx.O != 0 {
+		t.Errorf("Index is %d%+g, want 481+0", idx.I, idx.O)
+	}
+	// Elements from 480 to 500 are laid out twice, because cropped compounds are laid out in two passes.
+	if made > 60 {
+		t.Errorf("%d elements were made, want at most %d", made, 60)
+	}
+	if n := len(wo.auxold); n > 100 {
+		t.Errorf("%d Sorms are in the aux pool, want only the last tries", n)
+	}
+}
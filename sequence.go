@@ -1,8 +1,18 @@
 package contraption
 
-import "unicode/utf8"
+import (
+	"bufio"
+	"io"
+	"sort"
+	"unicode/utf8"
+
+	"golang.org/x/exp/slices"
+)
 
 // Sequence is the thing that can generate elements for a compound.
+//
+// Get can be called for the same elements several times in a frame, e.g. when the tree is laid out
+// twice for Hequal and Vequal, and must return equal elements every time.
 type Sequence interface {
 	Get(wo *World, j int, buf []*Sorm) (n int)
 	Length(wo *World) int
@@ -37,6 +47,222 @@ func SliceSequence2[T any](sl []T, produce func(int) *Sorm) Sequence {
 	return AdhocSequence(func(i int) *Sorm { return produce(i) }, func() int { return len(sl) })
 }
 
+// Sizehint is the count of elements kept by a buffered Sequence.
+type Sizehint int
+
+const (
+	BufferLarge  Sizehint = 16   // Complex and large elements
+	BufferMedium Sizehint = 128  // Moderately complex and medium-sized elements, like messages in a chat
+	BufferSmall  Sizehint = 1024 // Small and simple elements, like rows of data or bars of histograms
+)
+
+// buffer keeps values by indices. When it is full, values farthest from the new one are dropped,
+// because the seen elements of a Sequence are near each other. Indices are kept sorted,
+// so the farthest one is either the first or the last.
+type buffer[T any] struct {
+	hint  Sizehint
+	items map[int]T
+	is    []int
+}
+
+func (b *buffer[T]) get(i int) (v T, ok bool) {
+	v, ok = b.items[i]
+	return
+}
+
+func (b *buffer[T]) put(i int, v T) {
+	if b.items == nil {
+		b.items = map[int]T{}
+	}
+	if _, ok := b.items[i]; ok {
+		b.items[i] = v
+		return
+	}
+	for len(b.is) >= max(1, int(b.hint)) {
+		far := b.is[0]
+		if l := b.is[len(b.is)-1]; abs(l-i) > abs(far-i) {
+			far = l
+			b.is = b.is[:len(b.is)-1]
+		} else {
+			b.is = slices.Delete(b.is, 0, 1)
+		}
+		delete(b.items, far)
+	}
+	b.items[i] = v
+	b.is = slices.Insert(b.is, sort.SearchInts(b.is, i), i)
+}
+
+func (b *buffer[T]) invalidate(i, j int) {
+	l, r := sort.SearchInts(b.is, i), sort.SearchInts(b.is, j)
+	if l >= r {
+		return
+	}
+	for _, k := range b.is[l:r] {
+		delete(b.items, k)
+	}
+	b.is = slices.Delete(b.is, l, r)
+}
+
+// Buffer is a Sequence that keeps elements made by another Sequence, see BufferSequence.
+type Buffer struct {
+	buffer[[]sormrec]
+	q Sequence
+}
+
+// BufferSequence returns a Sequence that makes every element of q once and then copies it,
+// until it is invalidated. Buffer must outlive frames, so make it once.
+//
+// Elements are kept as they were made, so if their Conds or paints depend on anything
+// but the data of the Sequence, the Buffer must be invalidated when it changes.
+func BufferSequence(q Sequence, hint Sizehint) *Buffer {
+	return &Buffer{buffer: buffer[[]sormrec]{hint: hint}, q: q}
+}
+
+func (b *Buffer) Get(wo *World, j int, buf []*Sorm) (n int) {
+	n = b.q.Length(wo) - j
+	c := max(0, min(len(buf), n))
+	for i := 0; i < c; {
+		if rec, ok := b.get(j + i); ok {
+			buf[i] = wo.replay(rec)
+			i++
+			continue
+		}
+		// Make the run of missing elements at once.
+		r := i + 1
+		for r < c {
+			if _, ok := b.get(j + r); ok {
+				break
+			}
+			r++
+		}
+		m := getn(wo, b.q, j+i, buf[i:r])
+		for k, e := range buf[i : i+m] {
+			b.put(j+i+k, wo.record(e))
+		}
+		i = r
+	}
+	return
+}
+
+func (b *Buffer) Length(wo *World) int {
+	return b.q.Length(wo)
+}
+
+// Invalidate drops kept elements from i to j, so they are made again.
+func (b *Buffer) Invalidate(i, j int) {
+	b.invalidate(i, j)
+}
+
+// MemoBuffer is a Sequence that keeps data of elements, see MemoBufferSequence.
+type MemoBuffer[T any] struct {
+	buffer[T]
+	length  func() int
+	load    func(i int) T
+	produce func(i int, v T) *Sorm
+}
+
+// MemoBufferSequence returns a Sequence that loads data of every element once and keeps it
+// until it is invalidated, elements are produced from it on every frame.
+// MemoBuffer must outlive frames, so make it once.
+func MemoBufferSequence[T any](hint Sizehint, length func() int, load func(i int) T, produce func(i int, v T) *Sorm) *MemoBuffer[T] {
+	return &MemoBuffer[T]{buffer: buffer[T]{hint: hint}, length: length, load: load, produce: produce}
+}
+
+func (b *MemoBuffer[T]) Get(wo *World, j int, buf []*Sorm) (n int) {
+	n = b.length() - j
+	for i := range buf[:max(0, min(len(buf), n))] {
+		v, ok := b.get(j + i)
+		if !ok {
+			v = b.load(j + i)
+			b.put(j+i, v)
+		}
+		buf[i] = b.produce(j+i, v)
+	}
+	return
+}
+
+func (b *MemoBuffer[T]) Length(wo *World) int {
+	return b.length()
+}
+
+// Invalidate drops kept data from i to j, so it is loaded again.
+func (b *MemoBuffer[T]) Invalidate(i, j int) {
+	b.invalidate(i, j)
+}
+
+// sormrec is a copy of a Sorm made by a Sequence, kids are indices of other copies.
+type sormrec struct {
+	s                Sorm
+	kids, pres, mods []int
+	keyref           int // Key is the keyref-1-th copy
+}
+
+// record copies the tree of an element. Kids are copied before their parents.
+func (wo *World) record(e *Sorm) (rec []sormrec) {
+	seen := map[*Sorm]int{}
+	var walk func(s *Sorm) int
+	walk = func(s *Sorm) int {
+		if i, ok := seen[s]; ok {
+			return i
+		}
+		r := sormrec{s: *s}
+		if s.tag == 0 {
+			for _, k := range s.kids2(wo) {
+				r.kids = append(r.kids, walk(k))
+			}
+			for _, k := range s.pres(wo) {
+				r.pres = append(r.pres, walk(k))
+			}
+			for _, k := range s.mods(wo) {
+				r.mods = append(r.mods, walk(k))
+			}
+		}
+		seen[s] = len(rec)
+		rec = append(rec, r)
+		return len(rec) - 1
+	}
+	walk(e)
+	for i := range rec {
+		if k, ok := rec[i].s.key.(*Sorm); ok {
+			if j, ok := seen[k]; ok {
+				rec[i].keyref = j + 1
+			}
+		}
+	}
+	return
+}
+
+// replay makes a tree recorded by record.
+func (wo *World) replay(rec []sormrec) *Sorm {
+	ss := wo.tmpalloc(len(rec))
+	set := func(l, r int, is []int) {
+		for j, i := range is {
+			wo.pool[l+j] = ss[i]
+		}
+	}
+	for i, r := range rec {
+		s := wo.beginsorm()
+		z, z2, mark := s.z, s.z2, s.flags&flagSequenceMark
+		*s = r.s
+		s.z, s.z2 = z, z2
+		s.flags = s.flags&^flagSequenceMark | mark
+		if s.tag == 0 {
+			s.kidsl, s.kidsr = wo.alloc(len(r.kids))
+			set(s.kidsl, s.kidsr, r.kids)
+			s.presl, s.presr = wo.alloc(len(r.pres))
+			set(s.presl, s.presr, r.pres)
+			s.modsl, s.modsr = wo.alloc(len(r.mods))
+			set(s.modsl, s.modsr, r.mods)
+		}
+		if r.keyref > 0 {
+			s.key = ss[r.keyref-1]
+		}
+		wo.endsorm(s)
+		ss[i] = s
+	}
+	return ss[len(ss)-1]
+}
+
 type Scrollptr struct {
 	Index  int
 	Offset float64
@@ -45,49 +271,197 @@ type Scrollptr struct {
 	Dirty bool
 }
 
-// type appendSequence struct {
-// 	pre   bool
-// 	affix []Sorm
-// 	orig  Sequence
-// }
-
-// func (s *appendSequence) Get(wo *World, i int) Sorm {
-// 	if s.pre {
-// 		al := len(s.affix)
-// 		if i < al {
-// 			return s.affix[i]
-// 		} else {
-// 			return s.orig.Get(wo, al-i)
-// 		}
-// 	} else {
-// 		al := s.orig.Length(wo)
-// 		if i < al {
-// 			return s.orig.Get(wo, i)
-// 		} else {
-// 			return s.affix[al-i]
-// 		}
-// 	}
-// }
-
-// func (s *appendSequence) Length(wo *World) int {
-// 	return s.orig.Length(wo) + len(s.affix)
-// }
-
-// func PrependSeq(seq Sequence, wo *World, s ...Sorm) Sequence {
-// 	return &appendSequence{
-// 		pre:   true,
-// 		affix: wo.stash(s),
-// 		orig:  seq,
-// 	}
-// }
-
-// func AppendSeq(seq Sequence, wo *World, s ...Sorm) Sequence {
-// 	return &appendSequence{
-// 		pre:   false,
-// 		affix: wo.stash(s),
-// 		orig:  seq,
-// 	}
-// }
+// getn gets elements of q from j to buf and returns the count of gotten ones.
+func getn(wo *World, q Sequence, j int, buf []*Sorm) int {
+	if len(buf) == 0 {
+		return 0
+	}
+	return max(0, min(len(buf), q.Get(wo, j, buf)))
+}
+
+type concatSequence []Sequence
+
+func (s concatSequence) Get(wo *World, j int, buf []*Sorm) (n int) {
+	i, base := 0, 0
+	for _, q := range s {
+		l := q.Length(wo)
+		if at := j + i - base; at >= 0 && at < l {
+			i += getn(wo, q, at, buf[i:min(len(buf), i+l-at)])
+		}
+		base += l
+	}
+	return base - j
+}
+
+func (s concatSequence) Length(wo *World) (n int) {
+	for _, q := range s {
+		n += q.Length(wo)
+	}
+	return
+}
+
+// ConcatSequence returns a Sequence of elements of all qs, one after another.
+func ConcatSequence(qs ...Sequence) Sequence {
+	return concatSequence(qs)
+}
+
+type reverseSequence struct {
+	q Sequence
+}
+
+func (s reverseSequence) Get(wo *World, j int, buf []*Sorm) (n int) {
+	l := s.q.Length(wo)
+	n = l - j
+	c := max(0, min(len(buf), n))
+	c = getn(wo, s.q, l-j-c, buf[:c])
+	for a, b := 0, c-1; a < b; a, b = a+1, b-1 {
+		buf[a], buf[b] = buf[b], buf[a]
+	}
+	return
+}
+
+func (s reverseSequence) Length(wo *World) int {
+	return s.q.Length(wo)
+}
+
+// ReverseSequence returns a Sequence of elements of q from the last to the first.
+func ReverseSequence(q Sequence) Sequence {
+	return reverseSequence{q}
+}
+
+type fixedSequence []func() *Sorm
+
+func (s fixedSequence) Get(wo *World, j int, buf []*Sorm) (n int) {
+	for i := range buf[:max(0, min(len(buf), len(s)-j))] {
+		buf[i] = s[j+i]()
+	}
+	return len(s) - j
+}
+
+func (s fixedSequence) Length(wo *World) int {
+	return len(s)
+}
+
+// PrependSequence returns a Sequence of elements made by fs followed by elements of q.
+// Elements of Sequences must be made by them, so they are given by constructors, like in Between.
+func PrependSequence(q Sequence, fs ...func() *Sorm) Sequence {
+	return concatSequence{fixedSequence(fs), q}
+}
+
+// AppendSequence returns a Sequence of elements of q followed by elements made by fs.
+func AppendSequence(q Sequence, fs ...func() *Sorm) Sequence {
+	return concatSequence{q, fixedSequence(fs)}
+}
+
+// Filter is a Sequence of kept elements of another Sequence, see FilterSequence.
+type Filter struct {
+	q       Sequence
+	keep    func(i int) bool
+	is      []int // Indices of kept elements
+	scanned int   // Elements of q before it are checked by keep
+}
+
+// index checks only the elements of q which are not checked yet.
+// If q became shorter, the indices past its end are dropped.
+func (f *Filter) index(wo *World) []int {
+	l := f.q.Length(wo)
+	if l < f.scanned {
+		f.is = f.is[:sort.SearchInts(f.is, l)]
+		f.scanned = l
+	}
+	for ; f.scanned < l; f.scanned++ {
+		if f.keep(f.scanned) {
+			f.is = append(f.is, f.scanned)
+		}
+	}
+	return f.is
+}
+
+func (f *Filter) Get(wo *World, j int, buf []*Sorm) (n int) {
+	is := f.index(wo)
+	for i := range buf[:max(0, min(len(buf), len(is)-j))] {
+		getn(wo, f.q, is[j+i], buf[i:i+1])
+	}
+	return len(is) - j
+}
+
+func (f *Filter) Length(wo *World) int {
+	return len(f.index(wo))
+}
+
+// Invalidate checks the elements of q from i to j by keep again.
+func (f *Filter) Invalidate(i, j int) {
+	j = min(j, f.scanned)
+	if i >= j {
+		return
+	}
+	l, r := sort.SearchInts(f.is, i), sort.SearchInts(f.is, j)
+	var kept []int
+	for k := i; k < j; k++ {
+		if f.keep(k) {
+			kept = append(kept, k)
+		}
+	}
+	f.is = append(f.is[:l], append(kept, f.is[r:]...)...)
+}
+
+// FilterSequence returns a Sequence of elements of q at indices for which keep returns true.
+// Only kept elements are made.
+//
+// Every element is checked by keep once, so if keep depends on anything but the index,
+// the Filter must be invalidated when it changes. Filter must outlive frames to keep
+// its index, so make it once.
+func FilterSequence(q Sequence, keep func(i int) bool) *Filter {
+	return &Filter{q: q, keep: keep}
+}
+
+type mapSequence struct {
+	q Sequence
+	f func(j int, e *Sorm) *Sorm
+}
+
+func (s mapSequence) Get(wo *World, j int, buf []*Sorm) (n int) {
+	n = s.q.Get(wo, j, buf)
+	for i := range buf[:max(0, min(len(buf), n))] {
+		buf[i] = s.f(j+i, buf[i])
+	}
+	return
+}
+
+func (s mapSequence) Length(wo *World) int {
+	return s.q.Length(wo)
+}
+
+// MapSequence returns a Sequence of elements of q passed through f, for example to wrap them into compounds.
+func MapSequence(q Sequence, f func(j int, e *Sorm) *Sorm) Sequence {
+	return mapSequence{q, f}
+}
+
+type betweenSequence struct {
+	q   Sequence
+	sep func() *Sorm
+}
+
+func (s betweenSequence) Get(wo *World, j int, buf []*Sorm) (n int) {
+	n = s.Length(wo) - j
+	for i := range buf[:max(0, min(len(buf), n))] {
+		if (j+i)%2 == 0 {
+			getn(wo, s.q, (j+i)/2, buf[i:i+1])
+		} else {
+			buf[i] = s.sep()
+		}
+	}
+	return
+}
+
+func (s betweenSequence) Length(wo *World) int {
+	return max(0, 2*s.q.Length(wo)-1)
+}
+
+// BetweenSequence returns a Sequence of elements of q with a separator made by sep between every two of them.
+func BetweenSequence(q Sequence, sep func() *Sorm) Sequence {
+	return betweenSequence{q, sep}
+}
 
 type stringSeq struct {
 	string
@@ -115,3 +489,137 @@ func StringSeq(s string, produce func(rune) *Sorm) Sequence {
 		len:     utf8.RuneCountInString(s),
 	}
 }
+
+// Lines between offsets saved by RuneScannerSequence.
+const scannerCheckpoint = 64
+
+type runeScannerSeq struct {
+	rd      io.RuneScanner
+	sk      io.Seeker     // rd, if it can seek, or the reader under br
+	br      *bufio.Reader // rd, if it buffers a seeker, reset after seeking
+	produce func(line []rune) *Sorm
+	want    int  // Lines to have read
+	eof     bool // All lines were read
+	n       int  // Count of read lines
+
+	pos  int64   // Offset of the reader in bytes
+	line int     // Line at the reader
+	end  int64   // Offset of the first line that was not read
+	offs []int64 // Offsets of every scannerCheckpoint-th line
+
+	lines [][]rune       // All read lines, if rd can't seek
+	cache buffer[[]rune] // Recently read lines, if rd can seek
+}
+
+// RuneScannerSequence returns a Sequence of lines of a text read from rd, made with produce.
+//
+// Lines are read when they are requested, and some more after them, so the length grows
+// as the Sequence is scrolled to the end. If rd is an io.Seeker too, only recently read lines
+// are kept and others are read again from the nearest saved offset, otherwise all read lines are kept.
+// A bufio.Reader can't seek, so use ReadSeekerSequence for files.
+func RuneScannerSequence(rd io.RuneScanner, produce func(line []rune) *Sorm) Sequence {
+	s := &runeScannerSeq{rd: rd, produce: produce, want: int(BufferMedium)}
+	s.sk, _ = rd.(io.Seeker)
+	s.cache.hint = BufferSmall
+	return s
+}
+
+// ReadSeekerSequence is RuneScannerSequence for a reader that can seek but can't read runes,
+// like an *os.File. It is buffered and only recently read lines are kept.
+func ReadSeekerSequence(rd io.ReadSeeker, produce func(line []rune) *Sorm) Sequence {
+	br := bufio.NewReader(rd)
+	s := &runeScannerSeq{rd: br, sk: rd, br: br, produce: produce, want: int(BufferMedium)}
+	s.cache.hint = BufferSmall
+	return s
+}
+
+func (s *runeScannerSeq) Get(wo *World, j int, buf []*Sorm) (n int) {
+	s.want = max(s.want, j+len(buf)+int(BufferMedium))
+	n = s.Length(wo) - j
+	for i := range buf[:max(0, min(len(buf), n))] {
+		buf[i] = s.produce(s.get(j + i))
+	}
+	return
+}
+
+func (s *runeScannerSeq) Length(wo *World) int {
+	for !s.eof && s.n < s.want {
+		s.seek(s.n)
+		s.readline()
+	}
+	return s.n
+}
+
+// get returns the i-th line, which was read before.
+func (s *runeScannerSeq) get(i int) []rune {
+	if s.sk == nil {
+		return s.lines[i]
+	}
+	if l, ok := s.cache.get(i); ok {
+		return l
+	}
+	if s.line > i || i-s.line > scannerCheckpoint {
+		s.seek(i / scannerCheckpoint * scannerCheckpoint)
+	}
+	for {
+		l, ok := s.readline()
+		if !ok || s.line > i {
+			return l
+		}
+	}
+}
+
+// seek moves the reader to the i-th line, which is a checkpoint or the first line that was not read.
+func (s *runeScannerSeq) seek(i int) {
+	if s.line == i {
+		return
+	}
+	off := s.end
+	if i < s.n {
+		off = s.offs[i/scannerCheckpoint]
+	}
+	if _, err := s.sk.Seek(off, io.SeekStart); err != nil {
+		panic(err)
+	}
+	if s.br != nil {
+		s.br.Reset(s.sk.(io.Reader))
+	}
+	s.pos, s.line = off, i
+}
+
+// readline reads the line at the reader.
+func (s *runeScannerSeq) readline() (l []rune, ok bool) {
+	if s.line%scannerCheckpoint == 0 && s.line/scannerCheckpoint == len(s.offs) {
+		s.offs = append(s.offs, s.pos)
+	}
+	for {
+		r, size, err := s.rd.ReadRune()
+		if err == io.EOF {
+			if !ok {
+				s.eof = s.eof || s.line == s.n
+				return
+			}
+			break
+		} else if err != nil {
+			panic(err)
+		}
+		s.pos += int64(size)
+		ok = true
+		if r == '\n' {
+			break
+		}
+		l = append(l, r)
+	}
+	if s.line == s.n {
+		s.n++
+		s.end = s.pos
+		if s.sk == nil {
+			s.lines = append(s.lines, l)
+		}
+	}
+	if s.sk != nil {
+		s.cache.put(s.line, l)
+	}
+	s.line++
+	return
+}
@@ -0,0 +1,207 @@
+package contraption
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// keyseq is a Sequence of Sorms keyed by their indices.
+func keyseq(n int) Sequence {
+	return AdhocSequence(func(i int) *Sorm { return &Sorm{key: i} }, func() int { return n })
+}
+
+// sep makes a separator keyed by "sep".
+func sep() *Sorm { return &Sorm{key: "sep"} }
+
+// keys gets n elements of q from j and returns their keys.
+func keys(wo *World, q Sequence, j, n int) []any {
+	buf := make([]*Sorm, n)
+	n = getn(wo, q, j, buf)
+	var ks []any
+	for _, s := range buf[:n] {
+		ks = append(ks, s.key)
+	}
+	return ks
+}
+
+func TestSequenceAdapters(t *testing.T) {
+	wo := &World{Events: &Events{}}
+	tests := []struct {
+		name   string
+		q      Sequence
+		length int
+		j, n   int
+		keys   []any
+	}{
+		{"adhoc", keyseq(5), 5, 1, 3, []any{1, 2, 3}},
+		{"adhoc tail", keyseq(5), 5, 3, 10, []any{3, 4}},
+		{"concat", ConcatSequence(keyseq(2), keyseq(3)), 5, 1, 3, []any{1, 0, 1}},
+		{"concat empty", ConcatSequence(keyseq(0), keyseq(2), keyseq(0)), 2, 0, 5, []any{0, 1}},
+		{"reverse", ReverseSequence(keyseq(5)), 5, 1, 3, []any{3, 2, 1}},
+		{"reverse tail", ReverseSequence(keyseq(5)), 5, 3, 10, []any{1, 0}},
+		{"prepend", PrependSequence(keyseq(2), sep), 3, 0, 3, []any{"sep", 0, 1}},
+		{"append", AppendSequence(keyseq(2), sep, sep), 4, 1, 3, []any{1, "sep", "sep"}},
+		{"filter", FilterSequence(keyseq(10), func(i int) bool { return i%3 == 0 }), 4, 1, 10, []any{3, 6, 9}},
+		{"map", MapSequence(keyseq(3), func(j int, e *Sorm) *Sorm { e.key = e.key.(int) * 10; return e }), 3, 1, 2, []any{10, 20}},
+		{"between", BetweenSequence(keyseq(3), sep), 5, 1, 4, []any{"sep", 1, "sep", 2}},
+		{"between empty", BetweenSequence(keyseq(0), sep), 0, 0, 4, nil},
+		{"reverse concat", ReverseSequence(ConcatSequence(keyseq(2), keyseq(2))), 4, 0, 4, []any{1, 0, 1, 0}},
+	}
+	for _, tt := range tests {
+		if l := tt.q.Length(wo); l != tt.length {
+			t.Errorf("%s: length %d, want %d", tt.name, l, tt.length)
+		}
+		if ks := keys(wo, tt.q, tt.j, tt.n); !slices.Equal(ks, tt.keys) {
+			t.Errorf("%s: got %v, want %v", tt.name, ks, tt.keys)
+		}
+	}
+}
+
+func TestFilterSequenceMakesKept(t *testing.T) {
+	wo := &World{Events: &Events{}}
+	var made []int
+	q := AdhocSequence(func(i int) *Sorm { made = append(made, i); return &Sorm{key: i} }, func() int { return 10 })
+	f := FilterSequence(q, func(i int) bool { return i >= 7 })
+	keys(wo, f, 0, 3)
+	if !slices.Equal(made, []int{7, 8, 9}) {
+		t.Errorf("made %v, want only kept [7 8 9]", made)
+	}
+}
+
+func TestFilterInvalidate(t *testing.T) {
+	wo := &World{Events: &Events{}}
+	n, checks := 6, 0
+	odd := 1
+	q := AdhocSequence(func(i int) *Sorm { return &Sorm{key: i} }, func() int { return n })
+	f := FilterSequence(q, func(i int) bool { checks++; return i%2 == odd })
+	if ks := keys(wo, f, 0, 10); !slices.Equal(ks, []any{1, 3, 5}) {
+		t.Errorf("got %v, want [1 3 5]", ks)
+	}
+	keys(wo, f, 0, 10)
+	if checks != 6 {
+		t.Errorf("keep is called %d times, want once for every element", checks)
+	}
+
+	// Only the new elements are checked.
+	n = 9
+	if ks := keys(wo, f, 0, 10); !slices.Equal(ks, []any{1, 3, 5, 7}) {
+		t.Errorf("got %v, want [1 3 5 7]", ks)
+	}
+	if checks != 9 {
+		t.Errorf("keep is called %d times, want 9", checks)
+	}
+
+	odd = 0
+	f.Invalidate(2, 6)
+	if ks := keys(wo, f, 0, 10); !slices.Equal(ks, []any{1, 2, 4, 7}) {
+		t.Errorf("got %v after Invalidate, want [1 2 4 7]", ks)
+	}
+	n = 3
+	if ks := keys(wo, f, 0, 10); !slices.Equal(ks, []any{1, 2}) {
+		t.Errorf("got %v after shrinking, want [1 2]", ks)
+	}
+}
+
+func TestBufferPut(t *testing.T) {
+	b := buffer[int]{hint: 3}
+	for _, i := range []int{5, 1, 9, 3, 4} {
+		b.put(i, i*10)
+	}
+	// 9 is farther from 3 than 1, then 1 is farther from 4 than 5.
+	if !slices.Equal(b.is, []int{3, 4, 5}) || len(b.items) != 3 {
+		t.Errorf("kept %v, want [3 4 5]", b.is)
+	}
+	b.put(4, 0)
+	if v, _ := b.get(4); v != 0 || !slices.Equal(b.is, []int{3, 4, 5}) {
+		t.Errorf("replacing 4 kept %v with 4 = %d, want [3 4 5] with 0", b.is, v)
+	}
+	b.invalidate(4, 10)
+	if _, ok := b.get(5); ok || !slices.Equal(b.is, []int{3}) {
+		t.Errorf("kept %v after invalidation, want [3]", b.is)
+	}
+}
+
+func TestMemoBufferSequence(t *testing.T) {
+	loads := map[int]int{}
+	b := MemoBufferSequence(4, func() int { return 100 },
+		func(i int) int { loads[i]++; return i * i },
+		func(i int, v int) *Sorm { return &Sorm{key: v} })
+	if ks := keys(nil, b, 2, 3); !slices.Equal(ks, []any{4, 9, 16}) {
+		t.Errorf("got %v, want [4 9 16]", ks)
+	}
+	keys(nil, b, 2, 3)
+	for i := 2; i < 5; i++ {
+		if loads[i] != 1 {
+			t.Errorf("%d-th element was loaded %d times, want once", i, loads[i])
+		}
+	}
+
+	// The buffer is full, so the farthest elements are dropped first.
+	keys(nil, b, 5, 2)
+	keys(nil, b, 3, 1)
+	if loads[3] != 1 {
+		t.Errorf("near element was loaded %d times, want once", loads[3])
+	}
+	keys(nil, b, 2, 1)
+	if loads[2] != 2 {
+		t.Errorf("far element was loaded %d times, want twice", loads[2])
+	}
+
+	b.Invalidate(3, 4)
+	keys(nil, b, 3, 1)
+	if loads[3] != 2 {
+		t.Errorf("invalidated element was loaded %d times, want twice", loads[3])
+	}
+}
+
+// lineseq returns a Sequence of lines that keys elements with their lines.
+// The text is read by a RuneScanner, a RuneScanner that can seek, or a ReadSeeker.
+func lineseq(text string, reader string) Sequence {
+	produce := func(line []rune) *Sorm { return &Sorm{key: string(line)} }
+	switch reader {
+	case "seeker":
+		return RuneScannerSequence(strings.NewReader(text), produce)
+	case "readseeker":
+		return ReadSeekerSequence(struct{ io.ReadSeeker }{strings.NewReader(text)}, produce)
+	}
+	return RuneScannerSequence(bufio.NewReader(strings.NewReader(text)), produce)
+}
+
+func TestRuneScannerSequence(t *testing.T) {
+	var text strings.Builder
+	const n = 1000
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&text, "line %d ✓\n", i)
+	}
+	for _, seeker := range []string{"scanner", "seeker", "readseeker"} {
+		q := lineseq(text.String(), seeker)
+		if l := q.Length(nil); l != int(BufferMedium) {
+			t.Errorf("seeker %v: %d lines are read at first, want %d", seeker, l, BufferMedium)
+		}
+		// Scroll to the end and back.
+		for _, j := range []int{0, 500, 990, 3, 700, 64, 63, 0} {
+			want := []any{}
+			for i := j; i < min(n, j+5); i++ {
+				want = append(want, fmt.Sprintf("line %d ✓", i))
+			}
+			if ks := keys(nil, q, j, 5); !slices.Equal(ks, want) {
+				t.Errorf("seeker %v: lines from %d are %v, want %v", seeker, j, ks, want)
+			}
+		}
+		if l := q.Length(nil); l != n {
+			t.Errorf("seeker %v: %d lines, want %d", seeker, l, n)
+		}
+		if q := q.(*runeScannerSeq); seeker != "scanner" && q.lines != nil {
+			t.Errorf("seeker %v: all lines are kept", seeker)
+		}
+	}
+	q := lineseq("a\n\nb", "readseeker")
+	if ks := keys(nil, q, 0, 5); !slices.Equal(ks, []any{"a", "", "b"}) {
+		t.Errorf("got %q, want [a  b]", ks)
+	}
+}
@@ -248,6 +248,44 @@ func (wo *World) Void(w, h complex128) (s *Sorm) {
 }
 func voidrun(wo *World, s *Sorm) {}
 
+// Glue is an empty space between boxes for Hknuth and Vknuth.
+// Its size in the main axis of an aligner is width, can be reduced by at most shrink
+// and increased by stretch multiplied by the stretch ratio of a line.
+// Lines can be broken at a Glue that follows a box; such Glue disappears.
+//
+// Other aligners treat Glue as a Void of zero size.
+func (wo *World) Glue(width, shrink, stretch float64) (s *Sorm) {
+	s = wo.beginsorm()
+	s.tag = tagGlue
+	s.r = width
+	s.key = glue{shrink: shrink, stretch: stretch}
+	wo.endsorm(s)
+	return
+}
+func gluerun(wo *World, s *Sorm) {}
+
+type glue struct {
+	shrink, stretch float64
+}
+
+// Penalty marks a possible line break for Hknuth and Vknuth. The more cost is,
+// the less desirable the break is: math.Inf(1) forbids a break, math.Inf(-1) forces it.
+//
+// If replacement is not nil, it is shown at the end of a line broken at the Penalty,
+// e.g. a hyphen. Otherwise it is hidden. Other aligners never show the replacement
+// and don't give it any space.
+func (wo *World) Penalty(replacement *Sorm, cost float64) (s *Sorm) {
+	s = wo.beginsorm()
+	s.tag = tagPenalty
+	s.r = cost
+	if replacement != nil {
+		s.key = replacement
+	}
+	wo.endsorm(s)
+	return
+}
+func penaltyrun(wo *World, s *Sorm) {}
+
 func (wo *World) Equation(eqn Equation) (s *Sorm) {
 	s = wo.beginsorm()
 	s.tag = tagEquation
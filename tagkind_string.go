@@ -21,6 +21,10 @@ func _() {
 	_ = x[tagBottomUpText-10]
 	_ = x[tagSequence-11]
 	_ = x[tagIllustration-12]
+	_ = x[tagGlue-13]
+	_ = x[tagPenalty-14]
+	_ = x[tagParagraph-15]
+	_ = x[tagTextbox-16]
 	_ = x[tagHalign - -1]
 	_ = x[tagValign - -2]
 	_ = x[tagFill - -3]
@@ -34,8 +38,7 @@ func _() {
 	_ = x[tagScroll - -11]
 	_ = x[tagSource - -12]
 	_ = x[tagSink - -13]
-	_ = x[tagHscroll - -14]
-	_ = x[tagVscroll - -15]
+	_ = x[tagFocusable - -14]
 	_ = x[tagPosttransform - -101]
 	_ = x[tagTransform - -102]
 	_ = x[tagCrop - -103]
@@ -46,25 +49,35 @@ func _() {
 	_ = x[tagHfollow - -108]
 	_ = x[tagNoround - -109]
 	_ = x[tagRound - -110]
+	_ = x[tagHgrid - -111]
+	_ = x[tagVgrid - -112]
+	_ = x[tagHequal - -113]
+	_ = x[tagVequal - -114]
+	_ = x[tagHwords - -115]
+	_ = x[tagVwords - -116]
+	_ = x[tagHknuth - -117]
+	_ = x[tagVknuth - -118]
+	_ = x[tagHscroll - -119]
+	_ = x[tagVscroll - -120]
 }
 
 const (
-	_tagkind_name_0 = "DecimateDontDecimateHfollowVfollowLimitVshrinkHshrinkCropTransformPosttransform"
-	_tagkind_name_1 = "VscrollHscrollSinkSourceScrollBetweenCondstrokeCondfillCondIdentityStrokewidthStrokeFillValignHalignCompoundCircleRectRoundrectVoidEquationTextCanvasVectorTextTopDownTextBottomUpTextSequenceIllustration"
+	_tagkind_name_0 = "VscrollHscrollVknuthHknuthVwordsHwordsVequalHequalVgridHgridRoundNoroundHfollowVfollowLimitVshrinkHshrinkCropTransformPosttransform"
+	_tagkind_name_1 = "FocusableSinkSourceScrollBetweenCondstrokeCondfillCondIdentityStrokewidthStrokeFillValignHalignCompoundCircleRectRoundrectVoidEquationTextCanvasVectorTextTopDownTextBottomUpTextSequenceIllustrationGluePenaltyParagraphTextbox"
 )
 
 var (
-	_tagkind_index_0 = [...]uint8{0, 8, 20, 27, 34, 39, 46, 53, 57, 66, 79}
-	_tagkind_index_1 = [...]uint8{0, 7, 14, 18, 24, 30, 37, 47, 55, 59, 67, 78, 84, 88, 94, 100, 108, 114, 118, 127, 131, 139, 143, 149, 159, 170, 182, 190, 202}
+	_tagkind_index_0 = [...]uint8{0, 7, 14, 20, 26, 32, 38, 44, 50, 55, 60, 65, 72, 79, 86, 91, 98, 105, 109, 118, 131}
+	_tagkind_index_1 = [...]uint8{0, 9, 13, 19, 25, 32, 42, 50, 54, 62, 73, 79, 83, 89, 95, 103, 109, 113, 122, 126, 134, 138, 144, 154, 165, 177, 185, 197, 201, 208, 217, 224}
 )
 
 func (i tagkind) String() string {
 	switch {
-	case -110 <= i && i <= -101:
-		i -= -110
+	case -120 <= i && i <= -101:
+		i -= -120
 		return _tagkind_name_0[_tagkind_index_0[i]:_tagkind_index_0[i+1]]
-	case -15 <= i && i <= 12:
-		i -= -15
+	case -14 <= i && i <= 16:
+		i -= -14
 		return _tagkind_name_1[_tagkind_index_1[i]:_tagkind_index_1[i+1]]
 	default:
 		return "tagkind(" + strconv.FormatInt(int64(i), 10) + ")"
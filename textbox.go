@@ -0,0 +1,340 @@
+package contraption
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/neputevshina/contraption/nanovgo"
+	"github.com/neputevshina/geom"
+	"golang.design/x/clipboard"
+)
+
+// TextSequence is an editable text. Indices are in runes.
+//
+// Textbox edits the text only through these methods, so they can be journaled.
+type TextSequence interface {
+	Len() int
+	Insert(i int, rs []rune)
+	Delete(i, j int)
+	Copy(i, j int) []rune
+}
+
+// TextBuffer is the simplest TextSequence.
+type TextBuffer []rune
+
+func (b *TextBuffer) Len() int {
+	return len(*b)
+}
+
+func (b *TextBuffer) Insert(i int, rs []rune) {
+	*b = append((*b)[:i], append(rs[:len(rs):len(rs)], (*b)[i:]...)...)
+}
+
+func (b *TextBuffer) Delete(i, j int) {
+	*b = append((*b)[:i], (*b)[j:]...)
+}
+
+func (b *TextBuffer) Copy(i, j int) []rune {
+	return append([]rune(nil), (*b)[i:j]...)
+}
+
+// Textbox is a state of an editable single-line text.
+// Behavior follows https://rxi.github.io/textbox_behaviour.html.
+type Textbox struct {
+	Text TextSequence
+	// Selection is between Anchor and Caret, Caret is where the cursor is.
+	Caret, Anchor int
+
+	rect     geom.Rectangle // From the previous frame
+	dragging bool
+	last     time.Time
+}
+
+// Selection returns the selected range.
+func (tb *Textbox) Selection() (i, j int) {
+	return min(tb.Caret, tb.Anchor), max(tb.Caret, tb.Anchor)
+}
+
+type textbox struct {
+	*Textbox
+	font    *Font
+	cap     float64
+	runes   []rune
+	pos     []float64 // Caret positions at cap
+	focused bool
+}
+
+// NewTextbox returns a constructor of an editable text field, where size is the height of a capital letter.
+//
+// Events are handled when the field is constructed, so the changes are seen in the same frame.
+// Fill paints the text and the caret, Stroke paints the selection.
+//
+// The field is a Focusable compound which Identity is tb, so it receives keys only if
+// wo.Focused(tb), and it is focused by a click, by Tab or by wo.Focus(tb).
+func (wo *World) NewTextbox(font []byte) func(size float64, tb *Textbox) *Sorm {
+	name := strconv.FormatUint(rand.Uint64(), 36)
+	f, err := NewFont(wo.Vgo, font, name)
+	if err != nil {
+		panic(err)
+	}
+	return func(size float64, tb *Textbox) *Sorm {
+		t := &textbox{Textbox: tb, font: f, cap: size, focused: wo.Focused(tb)}
+		t.measure()
+		if tb.edit(wo, t) {
+			t.measure()
+		}
+
+		s := wo.beginsorm()
+		s.tag = tagTextbox
+		s.Size.X = t.pos[len(t.pos)-1] + 1
+		s.Size.Y = size
+		s.vecfont = f
+		s.key = t
+		wo.endsorm(s)
+		return wo.Compound(wo.Identity(tb), wo.Focusable(), s)
+	}
+}
+
+func (t *textbox) measure() {
+	t.runes = t.Text.Copy(0, t.Text.Len())
+	t.Caret = max(0, min(t.Caret, len(t.runes)))
+	t.Anchor = max(0, min(t.Anchor, len(t.runes)))
+	em := t.font.Captoem(t.cap)
+	t.pos = append(t.pos[:0], 0)
+	x := 0.0
+	for i, r := range t.runes {
+		if i == 0 {
+			x = t.font.TrueXBearing(r)
+		}
+		x += t.font.Advance(r)
+		t.pos = append(t.pos, x*em)
+	}
+}
+
+// hit returns the caret position nearest to x in the coordinates of the previous frame.
+func (t *textbox) hit(x float64) int {
+	if t.rect.Dy() == 0 {
+		return 0
+	}
+	x = (x - t.rect.Min.X) * t.cap / t.rect.Dy()
+	best := 0
+	for i, p := range t.pos {
+		if abs(p-x) < abs(t.pos[best]-x) {
+			best = i
+		}
+	}
+	return best
+}
+
+// edit applies the fresh event to the Textbox and reports if the text was changed.
+func (tb *Textbox) edit(wo *World, t *textbox) (changed bool) {
+	ev := wo.Events.Trace[0]
+	if ev.T == tb.last {
+		return
+	}
+	shift := ev.Mods&ModShift > 0
+	ctrl := ev.Mods&ModCtrl > 0
+
+	switch {
+	case wo.Match(`Click(1)`):
+		tb.last = ev.T
+		// Focus is moved by the click before, see (*World).activate.
+		if !ev.Pt.In(tb.rect) {
+			return
+		}
+		tb.dragging = true
+		tb.Caret = t.hit(ev.Pt.X)
+		if !shift {
+			tb.Anchor = tb.Caret
+		}
+		return
+
+	case wo.Match(`Hover`) && tb.dragging:
+		tb.last = ev.T
+		tb.Caret = t.hit(ev.Pt.X)
+		return
+
+	case wo.Match(`Unclick(1)`):
+		tb.last = ev.T
+		tb.dragging = false
+		return
+
+	case !wo.Match(`Press`) || !t.focused:
+		return
+	}
+	tb.last = ev.T
+	p := ev.E.(Press)
+
+	n := len(t.runes)
+	i, j := tb.Selection()
+	// move moves the caret and collapses or extends the selection.
+	move := func(to int) {
+		tb.Caret = max(0, min(to, n))
+		if !shift {
+			tb.Anchor = tb.Caret
+		}
+	}
+	// del deletes the selection or a range before or after the caret.
+	del := func(to int) {
+		if i == j {
+			i, j = min(tb.Caret, to), max(tb.Caret, to)
+		}
+		i, j = max(0, i), min(j, n)
+		if i < j {
+			tb.Text.Delete(i, j)
+			changed = true
+		}
+		tb.Caret, tb.Anchor = i, i
+	}
+
+	switch {
+	case p.Rune == RuneLeft || p.Key == KeyLeft:
+		switch {
+		case ctrl:
+			move(t.wordleft(tb.Caret))
+		case i != j && !shift:
+			move(i)
+		default:
+			move(tb.Caret - 1)
+		}
+	case p.Rune == RuneRight || p.Key == KeyRight:
+		switch {
+		case ctrl:
+			move(t.wordright(tb.Caret))
+		case i != j && !shift:
+			move(j)
+		default:
+			move(tb.Caret + 1)
+		}
+	case p.Rune == RuneUp || p.Key == KeyUp || p.Key == KeyHome:
+		move(0)
+	case p.Rune == RuneDown || p.Key == KeyDown || p.Key == KeyEnd:
+		move(n)
+	case p.Rune == RuneBackspace || p.Key == KeyBackspace:
+		if ctrl {
+			del(t.wordleft(tb.Caret))
+		} else {
+			del(tb.Caret - 1)
+		}
+	case p.Rune == RuneDelete || p.Key == KeyDelete:
+		if ctrl {
+			del(t.wordright(tb.Caret))
+		} else {
+			del(tb.Caret + 1)
+		}
+	case ctrl && p.Key == KeyA:
+		tb.Anchor, tb.Caret = 0, n
+	case ctrl && (p.Key == KeyC || p.Key == KeyX):
+		if i < j {
+			clipwrite(tb.Text.Copy(i, j))
+			if p.Key == KeyX {
+				del(i)
+			}
+		}
+	case ctrl && p.Key == KeyV:
+		rs := clipread()
+		del(i)
+		if len(rs) > 0 {
+			tb.Text.Insert(i, rs)
+			changed = true
+		}
+		tb.Caret, tb.Anchor = i+len(rs), i+len(rs)
+	case p.Rune >= ' ' && p.Rune != RuneDelete:
+		del(i)
+		tb.Text.Insert(i, []rune{p.Rune})
+		tb.Caret, tb.Anchor = i+1, i+1
+		changed = true
+	}
+	return
+}
+
+// wordleft returns the start of a word before i.
+func (t *textbox) wordleft(i int) int {
+	for i > 0 && unicode.IsSpace(t.runes[i-1]) {
+		i--
+	}
+	for i > 0 && !unicode.IsSpace(t.runes[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordright returns the end of a word after i.
+func (t *textbox) wordright(i int) int {
+	for i < len(t.runes) && unicode.IsSpace(t.runes[i]) {
+		i++
+	}
+	for i < len(t.runes) && !unicode.IsSpace(t.runes[i]) {
+		i++
+	}
+	return i
+}
+
+func textboxrun(wo *World, s *Sorm) {
+	t := s.key.(*textbox)
+	t.rect = s.Rectangle()
+	vgo := wo.Vgo
+	k := s.Size.Y / t.cap
+	x := func(i int) float64 { return s.p.X + t.pos[i]*k }
+
+	vgo.ResetTransform()
+	if i, j := t.Selection(); t.focused && i < j {
+		vgo.BeginPath()
+		if s.stroke != (nanovgo.Paint{}) {
+			vgo.SetFillPaint(s.stroke)
+		} else {
+			vgo.SetFillPaint(hexpaint(`#3390ff60`))
+		}
+		vgo.Rect(x(i), s.p.Y, x(j)-x(i), s.Size.Y)
+		vgo.Fill()
+	}
+	if s.fill == (nanovgo.Paint{}) {
+		return
+	}
+	vgo.SetFillPaint(s.fill)
+	if len(t.runes) > 0 {
+		vgo.BeginPath()
+		vgo.SetTransform(nanovgo.TranslateMatrix(float32(s.p.X), float32(s.p.Y+s.Size.Y)))
+		makealinerd(vgo, t.font, s.Size.Y, Runes(t.runes), false, true)
+		vgo.Fill()
+		vgo.ResetTransform()
+	}
+	if t.focused {
+		vgo.BeginPath()
+		vgo.Rect(x(t.Caret), s.p.Y, 1, s.Size.Y)
+		vgo.Fill()
+	}
+}
+
+var clip struct {
+	once sync.Once
+	ok   bool
+	buf  []rune // Used when the system clipboard is not available.
+}
+
+func clipinit() {
+	clip.once.Do(func() {
+		// Clipboard panics if built without cgo.
+		defer func() { _ = recover() }()
+		clip.ok = clipboard.Init() == nil
+	})
+}
+
+func clipread() []rune {
+	clipinit()
+	if clip.ok {
+		return []rune(string(clipboard.Read(clipboard.FmtText)))
+	}
+	return append([]rune(nil), clip.buf...)
+}
+
+func clipwrite(rs []rune) {
+	clipinit()
+	clip.buf = rs
+	if clip.ok {
+		clipboard.Write(clipboard.FmtText, []byte(string(rs)))
+	}
+}
@@ -0,0 +1,86 @@
+package contraption_test
+
+import (
+	"testing"
+
+	. "github.com/neputevshina/contraption"
+	"github.com/neputevshina/contraption/backends/headless"
+	"github.com/neputevshina/geom"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestTextboxFocus(t *testing.T) {
+	wer, wo := world(t, 100, 100)
+	wer.Push(
+		headless.Event{E: Hover{}, Pt: geom.Pt(2, 5)},
+		headless.Event{E: Click(1)},
+		headless.Event{E: Unclick(1)},
+		headless.Event{E: Press{Key: KeyA}, Char: 'a'},
+		headless.Event{E: Press{Key: KeyTab}},
+		headless.Event{E: Press{Key: KeyB}, Char: 'b'})
+	tbox := wo.NewTextbox(goregular.TTF)
+	one, two := TextBuffer("one"), TextBuffer("two")
+	a, b := &Textbox{Text: &one}, &Textbox{Text: &two}
+	frames(wo, 20, func(wo *World) *Sorm {
+		return wo.Compound(wo.Vfollow(), tbox(10, a), tbox(10, b))
+	})
+	if got, want := string(one), "aone"; got != want {
+		t.Errorf("first Textbox has %q, want %q", got, want)
+	}
+	if got, want := string(two), "btwo"; got != want {
+		t.Errorf("second Textbox has %q, want %q", got, want)
+	}
+	if !wo.Focused(b) || wo.Focused(a) {
+		t.Errorf("Tab did not move the focus to the second Textbox")
+	}
+}
+
+func TestTextboxEdit(t *testing.T) {
+	press := func(k Key) headless.Event { return headless.Event{E: Press{Key: k}} }
+	release := func(k Key) headless.Event { return headless.Event{E: Release{Key: k}} }
+	ctrl := func(k Key) []headless.Event { return []headless.Event{press(KeyLCtrl), press(k), release(KeyLCtrl)} }
+	shift := func(evs ...headless.Event) []headless.Event {
+		return append(append([]headless.Event{press(KeyLShift)}, evs...), release(KeyLShift))
+	}
+	cat := func(evss ...[]headless.Event) (evs []headless.Event) {
+		for _, e := range evss {
+			evs = append(evs, e...)
+		}
+		return
+	}
+	for _, tt := range []struct {
+		name          string
+		caret, anchor int
+		events        []headless.Event
+		text          string
+		wcaret, wanch int
+	}{
+		{"word left", 13, 13, cat(ctrl(KeyLeft), ctrl(KeyLeft)), "one two three", 4, 4},
+		{"word right", 0, 0, ctrl(KeyRight), "one two three", 3, 3},
+		{"word right from a space", 3, 3, ctrl(KeyRight), "one two three", 7, 7},
+		{"extend", 4, 4, shift(press(KeyRight), press(KeyRight)), "one two three", 6, 4},
+		{"extend by words", 13, 13, cat([]headless.Event{press(KeyLShift)}, ctrl(KeyLeft)), "one two three", 8, 13},
+		{"collapse left", 7, 4, []headless.Event{press(KeyLeft)}, "one two three", 4, 4},
+		{"collapse right", 4, 7, []headless.Event{press(KeyRight)}, "one two three", 7, 7},
+		{"delete word left", 7, 7, ctrl(KeyBackspace), "one  three", 4, 4},
+		{"delete word right", 3, 3, ctrl(KeyDelete), "one three", 3, 3},
+		{"delete selection", 7, 4, []headless.Event{press(KeyDelete)}, "one  three", 4, 4},
+		{"backspace at the start", 0, 0, []headless.Event{press(KeyBackspace)}, "one two three", 0, 0},
+		{"delete at the end", 13, 13, []headless.Event{press(KeyDelete)}, "one two three", 13, 13},
+		{"type over selection", 0, 3, []headless.Event{{E: Press{Key: KeyX}, Char: 'x'}}, "x two three", 1, 1},
+		{"cut and paste over selection", 0, 3,
+			cat(ctrl(KeyX), ctrl(KeyRight), shift(ctrl(KeyLeft)...), ctrl(KeyV)), " one three", 4, 4},
+	} {
+		wer, wo := world(t, 200, 100)
+		wer.Push(tt.events...)
+		buf := TextBuffer("one two three")
+		tb := &Textbox{Text: &buf, Caret: tt.caret, Anchor: tt.anchor}
+		wo.Focus(tb)
+		tbox := wo.NewTextbox(goregular.TTF)
+		frames(wo, 3*len(tt.events)+5, func(wo *World) *Sorm { return tbox(10, tb) })
+		if string(buf) != tt.text || tb.Caret != tt.wcaret || tb.Anchor != tt.wanch {
+			t.Errorf("%s: %q with Caret %d and Anchor %d, want %q with %d and %d",
+				tt.name, string(buf), tb.Caret, tb.Anchor, tt.text, tt.wcaret, tt.wanch)
+		}
+	}
+}
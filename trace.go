@@ -3,11 +3,9 @@ package contraption
 import (
 	"encoding/gob"
 	"io"
-	"log"
 	"os"
 	"time"
 
-	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/neputevshina/geom"
 )
 
@@ -45,6 +43,11 @@ type Events struct {
 	tempcur    int
 	regexps    map[string][]rinst
 	MatchCount int
+	hitmap     []hitrect
+	hitids     map[hitid]bool // Shapes of the hitmap
+	mods       Mods           // Held now
+	hint       Mods           // For the next emitted event, see Hold
+	hinted     bool
 
 	// 0 — normal operation
 	// 1 — recording
@@ -77,8 +80,24 @@ func (u *Events) SetDeadline(t time.Time) {
 	u.deadline = t
 }
 
+// animate keeps frames coming without waiting for events for a while,
+// it must be called on every frame of an animation.
+func (u *Events) animate() {
+	if d := u.Now.Add(100 * time.Millisecond); d.After(u.deadline) {
+		u.SetDeadline(d)
+	}
+}
+
+// now is the time of the Windower if it keeps one, or the system time.
+func (u *Events) now() time.Time {
+	if c, ok := u.wer.(clocker); ok {
+		return c.Now()
+	}
+	return time.Now()
+}
+
 func (wo *Events) next() bool {
-	now := time.Now()
+	now := wo.now()
 	if wo.tempcur == 0 {
 		// Don't update time on catching up events or else they won't be matched as fresh.
 		wo.Dt = wo.Now.Sub(now)
@@ -95,7 +114,7 @@ func (wo *Events) next() bool {
 		if wo.tempcur > 0 {
 			m := wo.temp[0]
 			copy(wo.temp[:], wo.temp[1:])
-			wo.trueemit(m.E, m.Pt, m.T)
+			wo.trueemit(m)
 			wo.tempcur--
 		} else {
 			if wo.Now.Compare(wo.deadline) >= 0 {
@@ -110,13 +129,51 @@ func (wo *Events) next() bool {
 }
 
 func (u *Events) emit(ev interface{}, pt geom.Point, t time.Time) {
-	m := EventPoint{ev, pt, t, 0, 0, 0}
+	m := EventPoint{E: ev, Pt: pt, T: t, Mods: u.hint, hinted: u.hinted}
+	u.hint, u.hinted = 0, false
 	// Skip events if application is lagging.
 	u.tempcur = min(len(u.temp)-1, u.tempcur)
 	u.temp[u.tempcur] = m
 	u.tempcur++
 }
 
+// Char sets the text entered with the pending key press, for windowers that receive text
+// separately from keys. It must be called after the Press was emitted, before the next frame.
+func (u *Events) Char(r rune) {
+	if u.tempcur == 0 {
+		return
+	}
+	m := &u.temp[u.tempcur-1]
+	if p, ok := m.E.(Press); ok {
+		p.Rune = r
+		m.E = p
+	}
+}
+
+// modsafter updates modifiers held after the event e.
+func (u *Events) modsafter(e EventPoint) Mods {
+	if e.hinted {
+		for _, f := range modsides {
+			switch h := e.Mods & f; {
+			case h == 0:
+				u.mods &^= f
+			case h != f:
+				u.mods = u.mods&^f | h
+			case u.mods&f == 0:
+				// Side is unknown, so it is the left one.
+				u.mods |= f &^ (f << 1)
+			}
+		}
+	}
+	switch ev := e.E.(type) {
+	case Press:
+		u.mods |= modof(ev.Key)
+	case Release:
+		u.mods &^= modof(ev.Key)
+	}
+	return u.mods
+}
+
 func (u *Events) develop() {
 	for i := range u.tr {
 		u.tr[i].z = 0
@@ -124,17 +181,33 @@ func (u *Events) develop() {
 	}
 }
 
+// Hold tells which modifier keys are held at the next emitted event, if the windower knows it.
+// If it doesn't know which of the left and right keys is held, it reports both, like ModCtrl.
+// Otherwise modifiers are tracked by Press and Release events.
+func (u *Events) Hold(mods Mods) {
+	u.hint, u.hinted = mods, true
+}
+
 // trueemit pushes the new event to the trace.
-func (u *Events) trueemit(ev interface{}, pt geom.Point, t time.Time) {
+func (u *Events) trueemit(e EventPoint) {
+	ev, pt, t := e.E, e.Pt, e.T
 	if _, yes := ev.(EventPoint); yes {
 		panic("can't emit EventPoint")
 	}
-	m := EventPoint{ev, pt, t, 0, 0, 0}
+	m := EventPoint{E: ev, Pt: pt, T: t, Mods: u.modsafter(e)}
 
 	if u.rec == 1 {
 		u.records = append(u.records, m)
 	}
 
+	// Remember what was under the pointer, so the event is matched :in shapes that have moved since.
+	m.mapped = u.hitmap != nil
+	for _, h := range u.hitmap {
+		if pt.In(h.r) {
+			m.hits = append(m.hits, h.id)
+		}
+	}
+
 	// // Push paint deadline further.
 	// u.SetDeadline(t.Add(100 * time.Millisecond))
 
@@ -209,6 +282,7 @@ type Matcher struct {
 	deadline time.Time
 	z        int
 	alwaysin bool
+	hit      hitid // Shape of the Cond, see (*EventPoint).in
 }
 
 func newMatcher(e *Events) Matcher {
@@ -260,7 +334,8 @@ func (m Matcher) Indef() Matcher {
 }
 
 func (m Matcher) Match(pattern Regexp) bool {
-	return m.u.match(pattern, m.rect, m.dur, m.deadline, m.z, m.alwaysin)
+	ok, _ := m.u.find(pattern, m, false)
+	return ok
 }
 
 func (u *Events) Match(pattern Regexp) bool {
@@ -306,26 +381,97 @@ func (u *Events) MatchInDeadline(pattern Regexp, rect geom.Rectangle, deadline t
 }
 
 func (u *Events) match(p Regexp, rect geom.Rectangle, dur time.Duration, deadline time.Time, z int, alwaysin bool) bool {
+	m := Matcher{u: u, rect: rect, dur: dur, deadline: deadline, z: z, alwaysin: alwaysin}
+	ok, _ := u.find(p, m, false)
+	return ok
+}
+
+// find is match that also returns positions of groups in the trace if capture is true, see rinterp.
+func (u *Events) find(p Regexp, m Matcher, capture bool) (bool, []int) {
 	pattern := string(p)
-	u.MatchCount++
 	r, ok := u.regexps[pattern]
 	if !ok {
 		var err error
 		r, err = rcompile(pattern)
 		if err != nil {
-			log.Println("error parsing pattern “" + pattern + "”")
 			panic(err)
 		}
 		u.regexps[pattern] = r
 	}
+	return u.exec(r, m, capture)
+}
 
-	ok, last := rinterp(r, u.Trace, rect, dur, deadline, z, alwaysin)
+// exec runs the compiled pattern r with parameters of m.
+func (u *Events) exec(r []rinst, m Matcher, capture bool) (bool, []int) {
+	u.MatchCount++
+	ncap := 0
+	if capture {
+		ncap = 2 * rgroups(r)
+	}
+
+	ok, last, caps := rinterp(r, u.Trace, m, ncap)
 
 	if ok {
 		u.Last = last
 		u.Last.Freshness = u.Now.Sub(u.Last.StartedAt)
 	}
-	return ok
+	return ok, caps
+}
+
+// Submatch is a part of the trace matched by a group of a regexp.
+type Submatch struct {
+	// Events are Trace[Begin:End], so the latest event is the first.
+	Events     []EventPoint
+	Begin, End int
+	// Duration is between the earliest and the latest event.
+	Duration time.Duration
+}
+
+// Found is a result of Find.
+type Found struct {
+	EventTraceLast
+	// Groups[0] is the whole match, Groups[k] is the k-th group counted by opening parentheses.
+	// If a group has matched nothing, its Events are nil. If it has matched several times,
+	// like in (Click(1) Unclick(1))+, it is the latest time.
+	Groups []Submatch
+}
+
+func (u *Events) found(caps []int) *Found {
+	f := &Found{EventTraceLast: u.Last, Groups: make([]Submatch, len(caps)/2)}
+	for k := range f.Groups {
+		i, j := caps[2*k], caps[2*k+1]
+		if i < 0 || j < 0 {
+			f.Groups[k] = Submatch{Begin: -1, End: -1}
+			continue
+		}
+		g := Submatch{Begin: i, End: j}
+		if i < j {
+			g.Events = append([]EventPoint(nil), u.Trace[i:j]...)
+			g.Duration = g.Events[0].T.Sub(g.Events[len(g.Events)-1].T)
+		}
+		f.Groups[k] = g
+	}
+	return f
+}
+
+// Find is Match that returns which events were matched by every group of the pattern.
+// It returns nil if the pattern is not matched.
+func (m Matcher) Find(pattern Regexp) *Found {
+	ok, caps := m.u.find(pattern, m, true)
+	if !ok {
+		return nil
+	}
+	return m.u.found(caps)
+}
+
+// Find is Match that returns which events were matched by every group of the pattern.
+// It returns nil if the pattern is not matched.
+func (u *Events) Find(pattern Regexp) *Found {
+	ok, caps := u.find(pattern, newMatcher(u), true)
+	if !ok {
+		return nil
+	}
+	return u.found(caps)
 }
 
 func NewEventTracer(wer Windower, replay io.Reader) *Events {
@@ -340,7 +486,9 @@ func NewEventTracer(wer Windower, replay io.Reader) *Events {
 	} else {
 		// Replay mode disables vsync.
 		// This is the simplest way to synchronize recorded events and state.
-		glfw.SwapInterval(0)
+		if v, ok := wer.(vsyncer); ok {
+			v.Vsync(false)
+		}
 		u.rec = 2
 		u.rec0 = time.Now()
 		u.playc = make(chan time.Time)
@@ -384,20 +384,23 @@ func must(err error) {
 	}
 }
 
+// Symbol is a reaction of an Activator to an event.
 type Symbol int
 
 const (
-	Silence Symbol = iota
-	Unfocus
-	Ack
+	Silence    Symbol = iota // Event is passed further
+	Deactivate               // Event is taken and the Activator is removed from the stack
+	Ack                      // Event is taken
 )
 
+// 1 pt = 1/72 in = 254/720 mm
+
 func mmtopt(mm float64) float64 {
-	return mm * 254 / 720
+	return mm * 720 / 254
 }
 
 func pttomm(pt float64) float64 {
-	return pt * 720 / 254
+	return pt * 254 / 720
 }
 
 func cceil(c complex128) complex128 {
@@ -0,0 +1,57 @@
+package contraption_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+	"time"
+
+	"github.com/neputevshina/contraption"
+	"github.com/neputevshina/contraption/backends/headless"
+	"github.com/neputevshina/contraption/backends/software"
+	"github.com/neputevshina/contraption/nanovgo"
+)
+
+// epoch is the Clock of headless Windowers in tests.
+var epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// world returns a headless World that renders with the software renderer.
+func world(t *testing.T, w, h int) (*headless.Windower, *contraption.World) {
+	t.Helper()
+	wer := headless.New(w, h, 1)
+	wer.Clock = epoch
+	wo := contraption.New(wer, software.New(), contraption.Config{})
+	return wer, wo
+}
+
+// frames runs n frames of wo. The root of every frame is returned by f, nil is an empty root.
+func frames(wo *contraption.World, n int, f func(wo *contraption.World) *contraption.Sorm) {
+	for i := 0; i < n && wo.Next(); i++ {
+		if s := f(wo); s != nil {
+			wo.Root(s)
+		} else {
+			wo.Root()
+		}
+		wo.Develop()
+	}
+}
+
+func TestZeroRenderer(t *testing.T) {
+	wer := headless.New(20, 20, 1)
+	wer.Clock = epoch
+	rer := &software.Renderer{}
+	wo := contraption.New(wer, rer, contraption.Config{})
+	red := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(red, red.Rect, image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	h := -1
+	frames(wo, 2, func(wo *contraption.World) *contraption.Sorm {
+		if h < 0 {
+			h = wo.Vgo.CreateImageFromGoImage(0, red)
+		}
+		return wo.Compound(wo.Rectangle(10, 10), wo.Fill(nanovgo.ImagePattern(0, 0, 10, 10, 0, h, 1)))
+	})
+	if c := rer.Image.RGBAAt(5, 5); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("image is drawn as %v, want red", c)
+	}
+}